@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"hello-world/config"
 	"hello-world/handlers"
 )
 
@@ -55,7 +56,7 @@ func BenchmarkDebugHandler(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		rr := httptest.NewRecorder()
-		handler := http.HandlerFunc(handlers.DebugHandler)
+		handler := handlers.DebugHandler(&config.Config{})
 		handler.ServeHTTP(rr, req)
 	}
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"hello-world/config"
+	"hello-world/handlers"
+	"hello-world/routes"
+)
+
+// action runs a command with the parsed configuration
+type action func(cfg *config.Config, stdout io.Writer) error
+
+// command is a subcommand of the binary
+type command struct {
+	name  string
+	usage string
+	// flags adds the command's own flags, next to the configuration flags, and returns its action
+	flags func(fs *flag.FlagSet) action
+	// ignoreConfigErrors runs the command even when the configuration is invalid
+	ignoreConfigErrors bool
+}
+
+var commands = []command{
+	{name: "serve", usage: "start the server (default)", flags: serveFlags},
+	{name: "healthcheck", usage: "probe the local server and exit non-zero if it is unhealthy", flags: healthcheckFlags},
+	{name: "routes", usage: "print the route table with methods and middleware", flags: routesFlags},
+	{name: "config", usage: "print the effective configuration with secrets masked", flags: configFlags},
+	{name: "version", usage: "print the build information", flags: versionFlags, ignoreConfigErrors: true},
+}
+
+// run executes the subcommand named by args[0], defaulting to serve, and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(stdout)
+		return 0
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+		printUsage(stderr)
+		return 2
+	}
+
+	cfg, action, err := parseCommand(cmd, args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if errors.Is(err, errInvalidConfig) {
+		return 1
+	}
+	if err != nil {
+		return 2
+	}
+	if err := action(cfg, stdout); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+// errInvalidConfig is returned by parseCommand once the configuration errors are printed
+var errInvalidConfig = errors.New("invalid configuration")
+
+// parseCommand loads the configuration from the file and the environment,
+// applies the command line on top of it and validates the result
+func parseCommand(cmd *command, args []string, stderr io.Writer) (*config.Config, action, error) {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [flags]\n\n%s\n\nFlags:\n", cmd.name, cmd.usage)
+		fs.PrintDefaults()
+	}
+	fs.String("config", os.Getenv("CONFIG_FILE"), "YAML, JSON or TOML configuration file (CONFIG_FILE)")
+	// The flag defaults only show the current values; the configuration is
+	// loaded again once -config is known
+	config.Load().RegisterFlags(fs)
+	action := cmd.flags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return nil, nil, errors.New("unexpected arguments")
+	}
+
+	cfg, err := reloadWithFlags(fs)()
+	if err != nil && !cmd.ignoreConfigErrors {
+		fmt.Fprintf(stderr, "%s: invalid configuration:\n  %s\n", cmd.name, strings.ReplaceAll(err.Error(), "\n", "\n  "))
+		return nil, nil, errInvalidConfig
+	}
+	return cfg, action, nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hello-world [command] [flags]\n\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.usage)
+	}
+	tw.Flush()
+	fmt.Fprintln(w, "\nRun \"hello-world <command> -h\" for the flags of a command.")
+}
+
+func serveFlags(fs *flag.FlagSet) action {
+	return func(cfg *config.Config, _ io.Writer) error {
+		serve(cfg, reloadWithFlags(fs))
+		return nil
+	}
+}
+
+// reloadWithFlags returns a loader that rereads the configuration file and
+// the environment, reapplies the flags set on fs, so SIGHUP keeps
+// command-line overrides, and validates the result
+func reloadWithFlags(fs *flag.FlagSet) func() (*config.Config, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if f := fs.Lookup("config"); f != nil {
+		path = f.Value.String()
+	}
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = f.Value.String() })
+	return func() (*config.Config, error) {
+		cfg, err := config.LoadFile(path)
+		overrides := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+		cfg.RegisterFlags(overrides)
+		for name, value := range set {
+			// Command-specific flags are not configuration and fail to set
+			_ = overrides.Set(name, value)
+		}
+		return cfg, errors.Join(err, cfg.Validate())
+	}
+}
+
+func healthcheckFlags(fs *flag.FlagSet) action {
+	url := fs.String("url", "", "URL to probe; defaults to /readyz on the listener serving the probes")
+	timeout := fs.Duration("timeout", 3*time.Second, "time to wait for a response")
+	return func(cfg *config.Config, stdout io.Writer) error {
+		target := *url
+		if target == "" {
+			target = readinessURL(cfg)
+		}
+		client := &http.Client{Timeout: *timeout}
+		resp, err := client.Get(target)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s returned %s", target, resp.Status)
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", target, resp.Status)
+		return nil
+	}
+}
+
+// readinessURL is the local /readyz, on the admin listener when there is one
+// since the probes move there with it
+func readinessURL(cfg *config.Config) string {
+	host, port := "127.0.0.1", cfg.Server.Port
+	if cfg.Admin.Addr != "" {
+		if h, p, err := net.SplitHostPort(cfg.Admin.Addr); err == nil {
+			port = p
+			if h != "" && h != "0.0.0.0" && h != "::" {
+				host = h
+			}
+		}
+	}
+	return "http://" + net.JoinHostPort(host, port) + "/readyz"
+}
+
+func routesFlags(fs *flag.FlagSet) action {
+	admin := fs.Bool("admin", false, "print the admin listener's routes instead")
+	return func(cfg *config.Config, stdout io.Writer) error {
+		table, err := routes.Table(cfg, *admin)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "METHODS\tPATH\tMIDDLEWARE")
+		for _, route := range table {
+			methods := "ANY"
+			if len(route.Methods) > 0 {
+				methods = strings.Join(route.Methods, ",")
+			}
+			middleware := "-"
+			if len(route.Middleware) > 0 {
+				middleware = strings.Join(route.Middleware, " > ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", methods, route.Path, middleware)
+		}
+		return tw.Flush()
+	}
+}
+
+func configFlags(fs *flag.FlagSet) action {
+	return func(cfg *config.Config, stdout io.Writer) error {
+		tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		for _, setting := range cfg.Settings() {
+			fmt.Fprintf(tw, "%s\t%s\n", setting.Name, setting.Value)
+		}
+		return tw.Flush()
+	}
+}
+
+func versionFlags(fs *flag.FlagSet) action {
+	asJSON := fs.Bool("json", false, "print the full build information, including dependencies, as JSON")
+	return func(_ *config.Config, stdout io.Writer) error {
+		build := handlers.BuildInfo()
+		if *asJSON {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(build)
+		}
+		dirty := ""
+		if build.Dirty {
+			dirty = ", dirty"
+		}
+		_, err := fmt.Fprintf(stdout, "hello-world %s (built %s, %s%s)\n", build.Commit, build.BuildTime, build.GoVersion, dirty)
+		return err
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hello-world/config"
+)
+
+func TestRunCommands(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	tests := []struct {
+		args     []string
+		exitCode int
+		output   string
+	}{
+		{[]string{"version"}, 0, "hello-world "},
+		{[]string{"version", "-json"}, 0, `"dependencies":`},
+		{[]string{"config", "-port", "9999", "-drain-timeout", "1m"}, 0, "drain.timeout"},
+		{[]string{"routes"}, 0, "/api/click"},
+		{[]string{"routes", "-admin"}, 0, "middleware.AdminOnly"},
+		{[]string{"healthcheck", "-url", healthy.URL + "/health"}, 0, "200 OK"},
+		{[]string{"healthcheck", "-url", unhealthy.URL + "/health"}, 1, "503"},
+		{[]string{"help"}, 0, "healthcheck"},
+		{[]string{"bogus"}, 2, "unknown command"},
+		{[]string{"version", "extra"}, 2, "unexpected arguments"},
+		{[]string{"config", "-port"}, 2, "flag needs an argument"},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		if code := run(test.args, &out, &out); code != test.exitCode {
+			t.Errorf("%v: expected exit code %d, got %d: %s", test.args, test.exitCode, code, out.String())
+		}
+		if !strings.Contains(out.String(), test.output) {
+			t.Errorf("%v: expected output to contain %q, got %s", test.args, test.output, out.String())
+		}
+	}
+}
+
+func TestConfigCommandAppliesFlags(t *testing.T) {
+	t.Setenv("PORT", "8081")
+	var out bytes.Buffer
+	run([]string{"config", "-port", "9999"}, &out, &out)
+	if !strings.Contains(out.String(), "9999") || strings.Contains(out.String(), "8081") {
+		t.Errorf("Flags should override the environment, got %s", out.String())
+	}
+}
+
+func TestReadinessURL(t *testing.T) {
+	tests := []struct {
+		admin string
+		want  string
+	}{
+		{"", "http://127.0.0.1:8080/readyz"},
+		{"127.0.0.1:9090", "http://127.0.0.1:9090/readyz"},
+		{":9090", "http://127.0.0.1:9090/readyz"},
+		{"[::1]:9090", "http://[::1]:9090/readyz"},
+	}
+	for _, test := range tests {
+		cfg := &config.Config{Server: config.ServerConfig{Port: "8080"}, Admin: config.AdminConfig{Addr: test.admin}}
+		if got := readinessURL(cfg); got != test.want {
+			t.Errorf("admin %q: expected %s, got %s", test.admin, test.want, got)
+		}
+	}
+}
+
+func TestReloadWithFlagsKeepsOverrides(t *testing.T) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	config.Load().RegisterFlags(fs)
+	if err := fs.Parse([]string{"-log-level", "debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("PORT", "9001")
+	reloaded, err := reloadWithFlags(fs)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Log.Level != "debug" || reloaded.Server.Port != "9001" {
+		t.Errorf("Expected the flag to win over the reloaded environment, got %q %q", reloaded.Log.Level, reloaded.Server.Port)
+	}
+}
+
+func TestInvalidConfigFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: 0\naccess_log:\n  format: xml\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := run([]string{"config", "-config", path}, &out, &out); code != 1 {
+		t.Errorf("Expected exit code 1, got %d: %s", code, out.String())
+	}
+	for _, want := range []string{"invalid configuration", "server.port", "access_log.format"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in the output, got %s", want, out.String())
+		}
+	}
+
+	// Fixing the value on the command line is enough
+	out.Reset()
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("ACCESS_LOG_FORMAT", "off")
+	if code := run([]string{"config", "-port", "9000"}, &out, &out); code != 0 {
+		t.Errorf("Expected the flag and environment to fix the file, got %d: %s", code, out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"version"}, &out, &out); code != 0 {
+		t.Errorf("version should not need a valid configuration, got %d: %s", code, out.String())
+	}
+}
@@ -0,0 +1,7 @@
+package config
+
+// CommitHash is set at build time via -ldflags "-X hello-world/config.CommitHash=<sha>"
+var CommitHash = "unknown"
+
+// BuildTime is set at build time via -ldflags "-X hello-world/config.BuildTime=<RFC 3339 time>"
+var BuildTime = "unknown"
@@ -1,20 +1,220 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
+	"time"
 )
 
+// Config is the application configuration. Every setting has a default (the
+// `default` tag) and can be set, in increasing precedence, from the
+// configuration file (the dotted `key` tags, e.g. "server.port"), from the
+// environment variable in its `env` tag and from command-line flags.
 type Config struct {
-	Port string
+	Server    ServerConfig    `key:"server"`
+	Admin     AdminConfig     `key:"admin"`
+	Log       LogConfig       `key:"log"`
+	AccessLog AccessLogConfig `key:"access_log"`
+	RateLimit RateLimitConfig `key:"rate_limit"`
+	Security  SecurityConfig  `key:"security"`
+	CORS      CORSConfig      `key:"cors"`
+	Telemetry TelemetryConfig `key:"telemetry"`
+	Profile   ProfileConfig   `key:"profile"`
+	Drain     DrainConfig     `key:"drain"`
 }
 
+type ServerConfig struct {
+	Port string `key:"port" env:"PORT" default:"8080"`
+	// ReadTimeout, WriteTimeout and IdleTimeout bound the public connections;
+	// the admin listener uses the read and idle timeouts only
+	ReadTimeout  time.Duration `key:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"15s"`
+	WriteTimeout time.Duration `key:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"30s"`
+	IdleTimeout  time.Duration `key:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" default:"60s"`
+
+	// RequestTimeout bounds every routed request and APITimeout the /api routes; zero disables them
+	RequestTimeout time.Duration `key:"request_timeout" env:"REQUEST_TIMEOUT" default:"15s"`
+	APITimeout     time.Duration `key:"api_timeout" env:"API_TIMEOUT" default:"5s"`
+
+	// TrustedProxies lists CIDRs or IPs of reverse proxies whose forwarding headers are honoured
+	TrustedProxies []string `key:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	// MaxBodyBytes limits request bodies of JSON endpoints; ClickMaxBodyBytes and
+	// WebhookMaxBodyBytes apply to /api/click and to report/webhook receivers
+	MaxBodyBytes        int64 `key:"max_body_bytes" env:"MAX_BODY_BYTES" default:"65536"`
+	ClickMaxBodyBytes   int64 `key:"click_max_body_bytes" env:"MAX_BODY_BYTES_CLICK" default:"1024"`
+	WebhookMaxBodyBytes int64 `key:"webhook_max_body_bytes" env:"MAX_BODY_BYTES_WEBHOOK" default:"1048576"`
+
+	// CompressionMinSize is the smallest response body that is compressed
+	CompressionMinSize int `key:"compression_min_size" env:"COMPRESSION_MIN_SIZE" default:"1024"`
+	// CompressionEncodings lists the enabled content codings in preference order; empty uses zstd, br, gzip
+	CompressionEncodings []string `key:"compression_encodings" env:"COMPRESSION_ENCODINGS"`
+}
+
+type AdminConfig struct {
+	// Token is the bearer token required by admin endpoints; empty disables them
+	Token string `key:"token" env:"ADMIN_TOKEN" secret:"true"`
+	// Addr moves health, profiling and admin routes to a separate listener,
+	// e.g. "127.0.0.1:9090"; empty serves them on the public port
+	Addr string `key:"addr" env:"ADMIN_ADDR"`
+}
+
+type LogConfig struct {
+	// Level is the initial minimum level of the root logger
+	Level string `key:"level" env:"LOG_LEVEL" default:"info"`
+	// ConsoleFormat selects the console sink format: "text" or "json"
+	ConsoleFormat string `key:"console_format" env:"LOG_FORMAT" default:"text"`
+	// ConsoleLevel and OTelLevel are the initial levels of each log sink
+	ConsoleLevel string `key:"console_level" env:"LOG_CONSOLE_LEVEL" default:"debug"`
+	OTelLevel    string `key:"otel_level" env:"LOG_OTEL_LEVEL" default:"debug"`
+	// ConsoleDropAttrs and OTelDropAttrs list attribute keys removed from each sink
+	ConsoleDropAttrs []string `key:"console_drop_attrs" env:"LOG_CONSOLE_DROP_ATTRS"`
+	OTelDropAttrs    []string `key:"otel_drop_attrs" env:"LOG_OTEL_DROP_ATTRS"`
+	// RedactKeys lists attribute keys redacted from logs and spans on top of the defaults
+	RedactKeys []string `key:"redact_keys" env:"REDACT_KEYS"`
+}
+
+type AccessLogConfig struct {
+	// Format is "json", "combined" or "off"
+	Format string `key:"format" env:"ACCESS_LOG_FORMAT" default:"json"`
+	// SampleRate is the fraction of fast 2xx and 3xx responses written to the
+	// access log; errors and slow requests are always written
+	SampleRate float64 `key:"sample_rate" env:"ACCESS_LOG_SAMPLE_RATE" default:"1"`
+	// SlowThreshold marks requests that are always logged
+	SlowThreshold time.Duration `key:"slow_threshold" env:"ACCESS_LOG_SLOW_THRESHOLD" default:"1s"`
+}
+
+// RateLimitConfig holds requests per second per client and the burst of each
+// limited route group; a zero rate disables the limit
+type RateLimitConfig struct {
+	API        float64 `key:"api" env:"RATE_LIMIT_API" default:"10"`
+	APIBurst   int     `key:"api_burst" env:"RATE_LIMIT_API_BURST" default:"20"`
+	Click      float64 `key:"click" env:"RATE_LIMIT_CLICK" default:"2"`
+	ClickBurst int     `key:"click_burst" env:"RATE_LIMIT_CLICK_BURST" default:"5"`
+	RUM        float64 `key:"rum" env:"RATE_LIMIT_RUM" default:"0.5"`
+	RUMBurst   int     `key:"rum_burst" env:"RATE_LIMIT_RUM_BURST" default:"10"`
+	// APIKeys lists the keys accepted in X-Api-Key; requests carrying one get a
+	// bucket of their own instead of sharing their FID's or client IP's
+	APIKeys []string `key:"api_keys" env:"RATE_LIMIT_API_KEYS" secret:"true"`
+}
+
+type SecurityConfig struct {
+	// CSPReportOnly reports Content-Security-Policy violations without enforcing the policy
+	CSPReportOnly bool `key:"csp_report_only" env:"CSP_REPORT_ONLY"`
+	// FrameAncestors lists origins allowed to embed the app; empty uses the Farcaster defaults
+	FrameAncestors []string `key:"frame_ancestors" env:"FRAME_ANCESTORS"`
+	// HSTSMaxAge is the Strict-Transport-Security max-age for HTTPS requests; zero disables it
+	HSTSMaxAge time.Duration `key:"hsts_max_age" env:"HSTS_MAX_AGE" default:"8760h"`
+}
+
+type CORSConfig struct {
+	// AllowedOrigins lists origins allowed to call /api/v1, e.g. "https://*.example.com"; empty disables CORS
+	AllowedOrigins []string `key:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	// AllowCredentials lets cross-origin requests include credentials
+	AllowCredentials bool `key:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// MaxAge is how long browsers cache preflight results
+	MaxAge time.Duration `key:"max_age" env:"CORS_MAX_AGE" default:"10m"`
+}
+
+type TelemetryConfig struct {
+	// Endpoint is the OTLP/HTTP collector as host:port; empty disables export
+	Endpoint string `key:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	// Insecure sends telemetry over plain HTTP instead of TLS
+	Insecure bool `key:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE" default:"true"`
+	// SampleRatio is the initial fraction of root traces sampled
+	SampleRatio float64 `key:"sample_ratio" env:"OTEL_TRACES_SAMPLER_ARG" default:"1"`
+	// MetricsInterval is the time between metric exports
+	MetricsInterval time.Duration `key:"metrics_interval" env:"OTEL_METRICS_INTERVAL" default:"30s"`
+}
+
+type ProfileConfig struct {
+	// Dir enables continuous profiling into this directory; empty disables it
+	Dir string `key:"dir" env:"PROFILE_DIR"`
+	// Interval is the time between continuous profiles and CPUDuration the length of each CPU profile
+	Interval    time.Duration `key:"interval" env:"PROFILE_INTERVAL" default:"10m"`
+	CPUDuration time.Duration `key:"cpu_duration" env:"PROFILE_CPU_DURATION" default:"10s"`
+	// Retention is how long continuous profiles are kept; zero keeps them forever
+	Retention time.Duration `key:"retention" env:"PROFILE_RETENTION" default:"24h"`
+}
+
+type DrainConfig struct {
+	// PreStopDelay keeps serving after readiness fails so load balancers can
+	// stop routing; Timeout bounds waiting for in-flight requests and
+	// FlushTimeout flushing telemetry
+	PreStopDelay time.Duration `key:"pre_stop_delay" env:"DRAIN_PRE_STOP_DELAY" default:"5s"`
+	Timeout      time.Duration `key:"timeout" env:"DRAIN_TIMEOUT" default:"15s"`
+	FlushTimeout time.Duration `key:"flush_timeout" env:"DRAIN_FLUSH_TIMEOUT" default:"5s"`
+}
+
+// Defaults returns the configuration made of the `default` tags
+func Defaults() *Config {
+	c := &Config{}
+	c.fields(func(f field) {
+		if value, ok := f.tag.Lookup("default"); ok {
+			if err := setValue(f.value, value); err != nil {
+				panic(fmt.Sprintf("config: default of %s: %v", f.key, err))
+			}
+		}
+	})
+	return c
+}
+
+// Load returns the configuration from the file named by CONFIG_FILE, if any,
+// and the environment. Invalid values are ignored in favour of the
+// lower-precedence value; use LoadFile to report them.
 func Load() *Config {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	c, _ := LoadFile(os.Getenv("CONFIG_FILE"))
+	return c
+}
+
+// LoadFile applies the file at path, unless path is empty, and then the
+// environment on top of the defaults. Every invalid value is reported in the
+// returned error, and the returned configuration keeps the lower-precedence
+// value for each of them, so it is usable either way.
+func LoadFile(path string) (*Config, error) {
+	c := Defaults()
+	var errs []error
+	if path != "" {
+		if err := c.applyFile(path); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	c.fields(func(f field) {
+		name, ok := f.tag.Lookup("env")
+		if !ok {
+			return
+		}
+		if value := os.Getenv(name); value != "" {
+			if err := setValue(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	})
+	return c, errors.Join(errs...)
+}
+
+// field is a setting of the configuration with its dotted file key
+type field struct {
+	key   string
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// fields calls fn for every setting in declaration order
+func (c *Config) fields(fn func(field)) {
+	walkFields(reflect.ValueOf(c).Elem(), "", fn)
+}
 
-	return &Config{
-		Port: port,
+func walkFields(v reflect.Value, prefix string, fn func(field)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		key := prefix + sf.Tag.Get("key")
+		if sf.Type.Kind() == reflect.Struct {
+			walkFields(v.Field(i), key+".", fn)
+			continue
+		}
+		fn(field{key: key, tag: sf.Tag, value: v.Field(i)})
 	}
 }
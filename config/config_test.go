@@ -1,23 +1,28 @@
 package config
 
 import (
+	"errors"
+	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
 	// Test default port when PORT env var is not set
 	os.Unsetenv("PORT")
 	config := Load()
-	if config.Port != "8080" {
-		t.Errorf("Expected default port 8080, got %s", config.Port)
+	if config.Server.Port != "8080" {
+		t.Errorf("Expected default port 8080, got %s", config.Server.Port)
 	}
 
 	// Test custom port when PORT env var is set
 	os.Setenv("PORT", "9000")
 	config = Load()
-	if config.Port != "9000" {
-		t.Errorf("Expected port 9000, got %s", config.Port)
+	if config.Server.Port != "9000" {
+		t.Errorf("Expected port 9000, got %s", config.Server.Port)
 	}
 
 	// Clean up
@@ -25,8 +30,385 @@ func TestLoad(t *testing.T) {
 }
 
 func TestConfigStruct(t *testing.T) {
-	config := &Config{Port: "3000"}
-	if config.Port != "3000" {
-		t.Errorf("Expected port 3000, got %s", config.Port)
+	config := &Config{Server: ServerConfig{Port: "3000"}}
+	if config.Server.Port != "3000" {
+		t.Errorf("Expected port 3000, got %s", config.Server.Port)
+	}
+}
+
+func TestLoadLogLevelAndAdminToken(t *testing.T) {
+	os.Unsetenv("LOG_LEVEL")
+	os.Unsetenv("ADMIN_TOKEN")
+	config := Load()
+	if config.Log.Level != "info" {
+		t.Errorf("Expected default log level info, got %s", config.Log.Level)
+	}
+	if config.Admin.Token != "" {
+		t.Errorf("Expected empty admin token, got %s", config.Admin.Token)
+	}
+
+	os.Setenv("LOG_LEVEL", "debug")
+	os.Setenv("ADMIN_TOKEN", "secret")
+	config = Load()
+	if config.Log.Level != "debug" {
+		t.Errorf("Expected log level debug, got %s", config.Log.Level)
+	}
+	if config.Admin.Token != "secret" {
+		t.Errorf("Expected admin token secret, got %s", config.Admin.Token)
+	}
+
+	// Clean up
+	os.Unsetenv("LOG_LEVEL")
+	os.Unsetenv("ADMIN_TOKEN")
+}
+
+func TestLoadLogSinks(t *testing.T) {
+	os.Setenv("LOG_FORMAT", "json")
+	os.Setenv("LOG_CONSOLE_DROP_ATTRS", "user_agent, http.path,,")
+	config := Load()
+
+	if config.Log.ConsoleFormat != "json" {
+		t.Errorf("Expected console format json, got %s", config.Log.ConsoleFormat)
+	}
+	if config.Log.ConsoleLevel != "debug" || config.Log.OTelLevel != "debug" {
+		t.Errorf("Expected sink levels to default to debug, got %s and %s", config.Log.ConsoleLevel, config.Log.OTelLevel)
+	}
+	if len(config.Log.ConsoleDropAttrs) != 2 || config.Log.ConsoleDropAttrs[1] != "http.path" {
+		t.Errorf("Expected trimmed drop list, got %v", config.Log.ConsoleDropAttrs)
+	}
+	if len(config.Log.OTelDropAttrs) != 0 {
+		t.Errorf("Expected empty OTel drop list, got %v", config.Log.OTelDropAttrs)
+	}
+
+	// Clean up
+	os.Unsetenv("LOG_FORMAT")
+	os.Unsetenv("LOG_CONSOLE_DROP_ATTRS")
+}
+
+func TestLoadAccessLog(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		wantFormat    string
+		wantRate      float64
+		wantThreshold time.Duration
+	}{
+		{"defaults", nil, "json", 1, time.Second},
+		{"custom", map[string]string{
+			"ACCESS_LOG_FORMAT":         "combined",
+			"ACCESS_LOG_SAMPLE_RATE":    "0.1",
+			"ACCESS_LOG_SLOW_THRESHOLD": "250ms",
+		}, "combined", 0.1, 250 * time.Millisecond},
+		{"invalid numbers fall back", map[string]string{
+			"ACCESS_LOG_SAMPLE_RATE":    "often",
+			"ACCESS_LOG_SLOW_THRESHOLD": "slow",
+		}, "json", 1, time.Second},
+	}
+
+	for _, test := range tests {
+		for key, value := range test.env {
+			t.Setenv(key, value)
+		}
+		config := Load()
+		if config.AccessLog.Format != test.wantFormat {
+			t.Errorf("%s: expected format %s, got %s", test.name, test.wantFormat, config.AccessLog.Format)
+		}
+		if config.AccessLog.SampleRate != test.wantRate {
+			t.Errorf("%s: expected sample rate %v, got %v", test.name, test.wantRate, config.AccessLog.SampleRate)
+		}
+		if config.AccessLog.SlowThreshold != test.wantThreshold {
+			t.Errorf("%s: expected slow threshold %v, got %v", test.name, test.wantThreshold, config.AccessLog.SlowThreshold)
+		}
+		for key := range test.env {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestLoadSecurityHeaders(t *testing.T) {
+	config := Load()
+	if config.Security.CSPReportOnly || len(config.Security.FrameAncestors) != 0 || config.Security.HSTSMaxAge != 365*24*time.Hour {
+		t.Errorf("Unexpected security header defaults: %+v", config)
+	}
+
+	t.Setenv("CSP_REPORT_ONLY", "true")
+	t.Setenv("FRAME_ANCESTORS", "'self', https://example.com")
+	t.Setenv("HSTS_MAX_AGE", "0")
+	config = Load()
+	if !config.Security.CSPReportOnly {
+		t.Error("Expected report-only CSP")
+	}
+	if len(config.Security.FrameAncestors) != 2 || config.Security.FrameAncestors[1] != "https://example.com" {
+		t.Errorf("Expected two frame ancestors, got %v", config.Security.FrameAncestors)
+	}
+	if config.Security.HSTSMaxAge != 0 {
+		t.Errorf("Expected HSTS disabled, got %v", config.Security.HSTSMaxAge)
+	}
+}
+
+func TestSettingsMasksSecrets(t *testing.T) {
+	cfg := &Config{
+		Server: ServerConfig{Port: "8080", TrustedProxies: []string{"10.0.0.0/8", "127.0.0.1"}, RequestTimeout: 15 * time.Second},
+		Admin:  AdminConfig{Token: "secret"},
+	}
+	values := make(map[string]string)
+	for _, setting := range cfg.Settings() {
+		values[setting.Name] = setting.Value
+	}
+
+	if values["admin.token"] != Masked {
+		t.Errorf("Expected the admin token to be masked, got %q", values["admin.token"])
+	}
+	if values["server.port"] != "8080" || values["server.trusted_proxies"] != "10.0.0.0/8, 127.0.0.1" || values["server.request_timeout"] != "15s" {
+		t.Errorf("Unexpected settings: %v", values)
+	}
+
+	cfg.Admin.Token = ""
+	for _, setting := range cfg.Settings() {
+		if setting.Name == "admin.token" && setting.Value != "" {
+			t.Errorf("An unset secret should stay empty, got %q", setting.Value)
+		}
+	}
+}
+
+func TestLoadProfiling(t *testing.T) {
+	config := Load()
+	if config.Profile.Dir != "" || config.Profile.Interval != 10*time.Minute || config.Profile.Retention != 24*time.Hour {
+		t.Errorf("Unexpected profiling defaults: %q %v %v", config.Profile.Dir, config.Profile.Interval, config.Profile.Retention)
+	}
+
+	t.Setenv("PROFILE_DIR", "/var/lib/profiles")
+	t.Setenv("PROFILE_CPU_DURATION", "30s")
+	config = Load()
+	if config.Profile.Dir != "/var/lib/profiles" || config.Profile.CPUDuration != 30*time.Second {
+		t.Errorf("Unexpected profiling settings: %q %v", config.Profile.Dir, config.Profile.CPUDuration)
+	}
+}
+
+func TestDefaultsAreValid(t *testing.T) {
+	if err := Defaults().Validate(); err != nil {
+		t.Errorf("Expected valid defaults, got %v", err)
+	}
+}
+
+// writeConfig writes content to a file named name in a temporary directory
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", `
+server:
+  port: 9000
+  read_timeout: 5s
+  trusted_proxies: [10.0.0.0/8, 127.0.0.1]
+access_log:
+  sample_rate: 0.25
+telemetry:
+  insecure: false
+`},
+		{"config.yml", `
+server: {port: "9000", read_timeout: 5s, trusted_proxies: "10.0.0.0/8, 127.0.0.1"}
+access_log: {sample_rate: 0.25}
+telemetry: {insecure: false}
+`},
+		{"config.json", `{
+  "server": {"port": 9000, "read_timeout": "5s", "trusted_proxies": ["10.0.0.0/8", "127.0.0.1"]},
+  "access_log": {"sample_rate": 0.25},
+  "telemetry": {"insecure": false}
+}`},
+		{"config.toml", `
+[server]
+port = 9000
+read_timeout = "5s"
+trusted_proxies = ["10.0.0.0/8", "127.0.0.1"]
+
+[access_log]
+sample_rate = 0.25
+
+[telemetry]
+insecure = false
+`},
+	}
+
+	for _, test := range tests {
+		cfg, err := LoadFile(writeConfig(t, test.name, test.content))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if cfg.Server.Port != "9000" || cfg.Server.ReadTimeout != 5*time.Second || cfg.AccessLog.SampleRate != 0.25 || cfg.Telemetry.Insecure {
+			t.Errorf("%s: unexpected settings: %+v %+v %+v", test.name, cfg.Server, cfg.AccessLog, cfg.Telemetry)
+		}
+		if len(cfg.Server.TrustedProxies) != 2 || cfg.Server.TrustedProxies[1] != "127.0.0.1" {
+			t.Errorf("%s: expected two trusted proxies, got %v", test.name, cfg.Server.TrustedProxies)
+		}
+		if cfg.Server.WriteTimeout != 30*time.Second {
+			t.Errorf("%s: expected unset keys to keep their defaults, got %v", test.name, cfg.Server.WriteTimeout)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeConfig(t, "config.yaml", "server:\n  port: 7000\nlog:\n  level: warn\n")
+	tests := []struct {
+		name      string
+		file      string
+		env       map[string]string
+		args      []string
+		wantPort  string
+		wantLevel string
+	}{
+		{"defaults", "", nil, nil, "8080", "info"},
+		{"file over defaults", file, nil, nil, "7000", "warn"},
+		{"environment over file", file, map[string]string{"PORT": "7001"}, nil, "7001", "warn"},
+		{"flags over environment", file, map[string]string{"PORT": "7001", "LOG_LEVEL": "error"}, []string{"-port", "7002"}, "7002", "error"},
+		{"invalid environment keeps the file value", file, map[string]string{"SERVER_READ_TIMEOUT": "soon"}, nil, "7000", "warn"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			cfg, _ := LoadFile(test.file)
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			cfg.RegisterFlags(fs)
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Port != test.wantPort || cfg.Log.Level != test.wantLevel {
+				t.Errorf("Expected port %s and level %s, got %s and %s", test.wantPort, test.wantLevel, cfg.Server.Port, cfg.Log.Level)
+			}
+			if cfg.Server.ReadTimeout != 15*time.Second {
+				t.Errorf("Expected the default read timeout, got %v", cfg.Server.ReadTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadFileReportsAllErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		env      map[string]string
+		wantErrs []string
+	}{
+		{"unknown keys", "config.yaml", "server:\n  prot: 80\nlogging:\n  level: debug\n", nil,
+			[]string{`unknown key "server.prot"`, `unknown key "logging.level"`}},
+		{"invalid values", "config.json", `{"server": {"read_timeout": 15, "max_body_bytes": "lots"}, "cors": {"allow_credentials": "maybe"}}`, nil,
+			[]string{`server.read_timeout: invalid duration "15"`, `server.max_body_bytes: invalid integer "lots"`, `cors.allow_credentials: invalid boolean "maybe"`}},
+		{"list for a single value", "config.toml", "[server]\nport = [80, 81]\n", nil,
+			[]string{"server.port: expected a single value, got a list"}},
+		{"file and environment", "config.yaml", "drain:\n  timeout: forever\n", map[string]string{"RATE_LIMIT_API": "fast"},
+			[]string{`drain.timeout: invalid duration "forever"`, `RATE_LIMIT_API: invalid number "fast"`}},
+		{"unsupported format", "config.ini", "port = 80", nil, []string{`unsupported format ".ini"`}},
+		{"syntax error", "config.json", `{"server": `, nil, []string{"config.json: unexpected end of JSON input"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			cfg, err := LoadFile(writeConfig(t, test.file, test.content))
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range test.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in the error, got:\n%v", want, err)
+				}
+			}
+			if cfg == nil || cfg.Server.ReadTimeout != 15*time.Second || cfg.Drain.Timeout != 15*time.Second {
+				t.Errorf("Expected invalid values to keep their defaults, got %+v", cfg)
+			}
+		})
+	}
+
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file to be reported, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *Config)
+		wantErrs []string
+	}{
+		{"valid", func(c *Config) { c.Admin.Addr = "127.0.0.1:9090" }, nil},
+		{"port", func(c *Config) { c.Server.Port = "http" }, []string{`server.port: invalid port "http"`}},
+		{"port range", func(c *Config) { c.Server.Port = "70000" }, []string{"server.port: invalid port"}},
+		{"admin address", func(c *Config) { c.Admin.Addr = "localhost" }, []string{"admin.addr: address localhost: missing port"}},
+		{"admin port clash", func(c *Config) { c.Admin.Addr = "127.0.0.1:8080" }, []string{"admin.addr: must not use the public port 8080"}},
+		{"negative values", func(c *Config) {
+			c.Server.ReadTimeout = -time.Second
+			c.Server.MaxBodyBytes = -1
+			c.RateLimit.API = -1
+		}, []string{"server.read_timeout: must not be negative", "server.max_body_bytes: must not be negative", "rate_limit.api: must not be negative"}},
+		{"log levels and formats", func(c *Config) {
+			c.Log.Level = "loud"
+			c.Log.OTelLevel = "quiet"
+			c.Log.ConsoleFormat = "xml"
+			c.AccessLog.Format = "common"
+		}, []string{`log.level: invalid log level "loud"`, `log.otel_level: invalid log level "quiet"`, "log.console_format: must be text or json", "access_log.format: must be json, combined or off"}},
+		{"ratios", func(c *Config) {
+			c.AccessLog.SampleRate = 1.5
+			c.Telemetry.SampleRatio = 2
+		}, []string{"access_log.sample_rate: must be between 0 and 1", "telemetry.sample_ratio: must be between 0 and 1"}},
+		{"lists", func(c *Config) {
+			c.Server.TrustedProxies = []string{"10.0.0.0/8", "10.0.0.0/33", "proxy.local"}
+			c.Server.CompressionEncodings = []string{"gzip", "deflate"}
+		}, []string{`invalid IP or CIDR "10.0.0.0/33"`, `invalid IP or CIDR "proxy.local"`, `unsupported encoding "deflate"`}},
+		{"credentials for any origin", func(c *Config) {
+			c.CORS.AllowedOrigins = []string{"https://app.example.com", "*"}
+			c.CORS.AllowCredentials = true
+		}, []string{`cors.allowed_origins: "*" would let any site make credentialed requests`}},
+		{"credentials for listed origins", func(c *Config) {
+			c.CORS.AllowedOrigins = []string{"https://*.example.com"}
+			c.CORS.AllowCredentials = true
+		}, nil},
+		{"rate limit burst", func(c *Config) { c.RateLimit.ClickBurst = 0 }, []string{"rate_limit.click_burst: must be at least 1"}},
+		{"disabled rate limit", func(c *Config) { c.RateLimit.Click, c.RateLimit.ClickBurst = 0, 0 }, nil},
+		{"profile durations", func(c *Config) {
+			c.Profile.Dir = "/tmp/profiles"
+			c.Profile.CPUDuration = c.Profile.Interval
+		}, []string{"profile.cpu_duration: must be shorter than profile.interval"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Defaults()
+			test.modify(cfg)
+			err := cfg.Validate()
+			if len(test.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range test.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in the error, got:\n%v", want, err)
+				}
+			}
+			if got := len(strings.Split(err.Error(), "\n")); got != len(test.wantErrs) {
+				t.Errorf("Expected %d errors, got %d:\n%v", len(test.wantErrs), got, err)
+			}
+		})
 	}
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// applyFile sets the settings found in the YAML, JSON or TOML file at path,
+// chosen by its extension. Unknown keys and invalid values are all reported.
+func (c *Config) applyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".json":
+		err = json.Unmarshal(data, &doc)
+	case ".toml":
+		_, err = toml.Decode(string(data), &doc)
+	default:
+		return fmt.Errorf("%s: unsupported format %q, use .yaml, .json or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]any{}
+	flatten("", doc, values)
+	fields := map[string]field{}
+	c.fields(func(f field) { fields[f.key] = f })
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		f, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, key))
+			continue
+		}
+		if err := setFileValue(f.value, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flatten collects the values of nested tables under dotted keys
+func flatten(prefix string, doc map[string]any, values map[string]any) {
+	for key, value := range doc {
+		if table, ok := value.(map[string]any); ok {
+			flatten(prefix+key+".", table, values)
+			continue
+		}
+		values[prefix+key] = value
+	}
+}
+
+// setFileValue sets v from a decoded scalar, or from a list for list settings
+func setFileValue(v reflect.Value, value any) error {
+	list, isList := value.([]any)
+	if !isList {
+		s, err := scalarString(value)
+		if err != nil {
+			return err
+		}
+		return setValue(v, s)
+	}
+	if v.Kind() != reflect.Slice {
+		return errors.New("expected a single value, got a list")
+	}
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		s, err := scalarString(item)
+		if err != nil {
+			return err
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+	if len(items) == 0 {
+		items = nil
+	}
+	v.Set(reflect.ValueOf(items))
+	return nil
+}
+
+// scalarString renders a decoded scalar as the text setValue parses
+func scalarString(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case uint64:
+		return strconv.FormatUint(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// setValue parses s into v according to its type; lists are comma-separated
+// and durations are written like "500ms"
+func setValue(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == reflect.TypeFor[time.Duration]():
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(i)
+	case v.CanFloat():
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(s)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value into trimmed, non-empty values
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
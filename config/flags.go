@@ -0,0 +1,19 @@
+package config
+
+import "flag"
+
+// RegisterFlags adds command-line flags for the most commonly overridden
+// settings, defaulting to the values already in c, so parsing fs applies them
+// on top of the file and environment. Secrets such as the admin token have no
+// flag, as command lines are visible to other processes.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Server.Port, "port", c.Server.Port, "public HTTP port (PORT)")
+	fs.StringVar(&c.Admin.Addr, "admin-addr", c.Admin.Addr, "address of the admin listener, e.g. 127.0.0.1:9090 (ADMIN_ADDR)")
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, "root log level (LOG_LEVEL)")
+	fs.StringVar(&c.Log.ConsoleFormat, "log-format", c.Log.ConsoleFormat, `console log format, "text" or "json" (LOG_FORMAT)`)
+	fs.StringVar(&c.AccessLog.Format, "access-log-format", c.AccessLog.Format, `access log format, "json", "combined" or "off" (ACCESS_LOG_FORMAT)`)
+	fs.DurationVar(&c.Server.RequestTimeout, "request-timeout", c.Server.RequestTimeout, "timeout of routed requests (REQUEST_TIMEOUT)")
+	fs.StringVar(&c.Profile.Dir, "profile-dir", c.Profile.Dir, "directory for continuous profiles (PROFILE_DIR)")
+	fs.DurationVar(&c.Drain.PreStopDelay, "drain-pre-stop-delay", c.Drain.PreStopDelay, "time to keep serving after readiness fails (DRAIN_PRE_STOP_DELAY)")
+	fs.DurationVar(&c.Drain.Timeout, "drain-timeout", c.Drain.Timeout, "time to wait for in-flight requests on shutdown (DRAIN_TIMEOUT)")
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Masked replaces the value of secret settings that are set
+const Masked = "********"
+
+// Setting is a configuration field rendered for diagnostics
+type Setting struct {
+	Name  string
+	Value string
+}
+
+// Settings lists the effective configuration in field order, named by file
+// key such as "server.port". Fields tagged `secret:"true"` are masked, so the
+// result is safe to show to operators.
+func (c *Config) Settings() []Setting {
+	var settings []Setting
+	c.fields(func(f field) {
+		value := formatSetting(f.value)
+		if f.tag.Get("secret") == "true" && value != "" {
+			value = Masked
+		}
+		settings = append(settings, Setting{Name: f.key, Value: value})
+	})
+	return settings
+}
+
+func formatSetting(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate reports every invalid setting together, each prefixed with its
+// file key, so an operator can fix the configuration in one pass
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+	}
+
+	// Sizes, rates and durations are never meaningfully negative
+	c.fields(func(f field) {
+		if (f.value.CanInt() && f.value.Int() < 0) || (f.value.CanFloat() && f.value.Float() < 0) {
+			fail(f.key, "must not be negative, got %v", f.value.Interface())
+		}
+	})
+
+	port, err := parsePort(c.Server.Port)
+	if err != nil {
+		fail("server.port", "%v", err)
+	}
+	if c.Admin.Addr != "" {
+		_, adminPort, err := net.SplitHostPort(c.Admin.Addr)
+		if err == nil {
+			var p int
+			if p, err = parsePort(adminPort); err == nil && p == port {
+				err = fmt.Errorf("must not use the public port %d", port)
+			}
+		}
+		if err != nil {
+			fail("admin.addr", "%v", err)
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		var err error
+		if strings.Contains(proxy, "/") {
+			_, err = netip.ParsePrefix(proxy)
+		} else {
+			_, err = netip.ParseAddr(proxy)
+		}
+		if err != nil {
+			fail("server.trusted_proxies", "invalid IP or CIDR %q", proxy)
+		}
+	}
+	for _, encoding := range c.Server.CompressionEncodings {
+		if !slices.Contains([]string{"zstd", "br", "gzip"}, encoding) {
+			fail("server.compression_encodings", "unsupported encoding %q, use zstd, br or gzip", encoding)
+		}
+	}
+
+	for _, level := range []struct{ key, value string }{
+		{"log.level", c.Log.Level},
+		{"log.console_level", c.Log.ConsoleLevel},
+		{"log.otel_level", c.Log.OTelLevel},
+	} {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(strings.TrimSpace(level.value))); err != nil {
+			fail(level.key, "invalid log level %q", level.value)
+		}
+	}
+	if !slices.Contains([]string{"text", "json"}, c.Log.ConsoleFormat) {
+		fail("log.console_format", "must be text or json, got %q", c.Log.ConsoleFormat)
+	}
+	if !slices.Contains([]string{"json", "combined", "off"}, c.AccessLog.Format) {
+		fail("access_log.format", "must be json, combined or off, got %q", c.AccessLog.Format)
+	}
+	if c.AccessLog.SampleRate > 1 {
+		fail("access_log.sample_rate", "must be between 0 and 1, got %v", c.AccessLog.SampleRate)
+	}
+	if c.Telemetry.SampleRatio > 1 {
+		fail("telemetry.sample_ratio", "must be between 0 and 1, got %v", c.Telemetry.SampleRatio)
+	}
+
+	for _, limit := range []struct {
+		key   string
+		rate  float64
+		burst int
+	}{
+		{"rate_limit.api_burst", c.RateLimit.API, c.RateLimit.APIBurst},
+		{"rate_limit.click_burst", c.RateLimit.Click, c.RateLimit.ClickBurst},
+		{"rate_limit.rum_burst", c.RateLimit.RUM, c.RateLimit.RUMBurst},
+	} {
+		if limit.rate > 0 && limit.burst < 1 {
+			fail(limit.key, "must be at least 1 when the rate limit is enabled, got %d", limit.burst)
+		}
+	}
+
+	if c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		fail("cors.allowed_origins", `"*" would let any site make credentialed requests; list the origins when cors.allow_credentials is set`)
+	}
+
+	if c.Profile.Dir != "" && c.Profile.CPUDuration >= c.Profile.Interval {
+		fail("profile.cpu_duration", "must be shorter than profile.interval (%v), got %v", c.Profile.Interval, c.Profile.CPUDuration)
+	}
+
+	return errors.Join(errs...)
+}
+
+// parsePort parses a TCP port number between 1 and 65535
+func parsePort(s string) (int, error) {
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q, must be between 1 and 65535", s)
+	}
+	return port, nil
+}
@@ -3,7 +3,10 @@ module hello-world
 go 1.24.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/andybalholm/brotli v1.1.1
 	github.com/gorilla/mux v1.8.1
+	github.com/klauspost/compress v1.18.0
 	go.opentelemetry.io/contrib/bridges/otelslog v0.12.0
 	go.opentelemetry.io/otel v1.37.0
 	go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp v0.13.0
@@ -15,6 +18,7 @@ require (
 	go.opentelemetry.io/otel/sdk/log v0.13.0
 	go.opentelemetry.io/otel/sdk/metric v1.37.0
 	go.opentelemetry.io/otel/trace v1.37.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
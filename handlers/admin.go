@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"hello-world/middleware"
+)
+
+type logLevelRequest struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+}
+
+type samplerRequest struct {
+	Ratio *float64 `json:"ratio"`
+}
+
+// LogLevelHandler reports logger levels on GET and changes one on PUT/POST
+func LogLevelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		if req.Logger == "" {
+			req.Logger = middleware.RootLogger
+		}
+		level, err := middleware.ParseLevel(req.Level)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		middleware.ChangeLogLevel(r.Context(), req.Logger, level, "admin_api")
+	}
+
+	levels := make(map[string]string)
+	for name, level := range middleware.LogLevels() {
+		levels[name] = level.String()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"levels": levels})
+}
+
+// SamplerHandler reports the trace sampling ratio on GET and changes it on PUT/POST
+func SamplerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		var req samplerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Ratio == nil {
+			writeJSONError(w, http.StatusBadRequest, "body must be {\"ratio\": <0..1>}")
+			return
+		}
+		middleware.ChangeSampleRatio(r.Context(), *req.Ratio, "admin_api")
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ratio": middleware.TraceSampler().Ratio()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
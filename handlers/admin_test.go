@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"hello-world/middleware"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	defer middleware.SetLogLevel(middleware.RootLogger, slog.LevelInfo)
+
+	req := httptest.NewRequest("PUT", "/admin/log-level", strings.NewReader(`{"logger":"root","level":"debug"}`))
+	rr := httptest.NewRecorder()
+	LogLevelHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var body struct {
+		Levels map[string]string `json:"levels"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Levels["root"] != "DEBUG" {
+		t.Errorf("Expected root level DEBUG, got %q", body.Levels["root"])
+	}
+}
+
+func TestLogLevelHandlerInvalidLevel(t *testing.T) {
+	req := httptest.NewRequest("PUT", "/admin/log-level", strings.NewReader(`{"level":"loud"}`))
+	rr := httptest.NewRecorder()
+	LogLevelHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestSamplerHandler(t *testing.T) {
+	defer middleware.TraceSampler().SetRatio(1)
+
+	req := httptest.NewRequest("POST", "/admin/sampler", strings.NewReader(`{"ratio":0.25}`))
+	rr := httptest.NewRecorder()
+	SamplerHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if got := middleware.TraceSampler().Ratio(); got != 0.25 {
+		t.Errorf("Expected ratio 0.25, got %v", got)
+	}
+
+	req = httptest.NewRequest("POST", "/admin/sampler", strings.NewReader(`{}`))
+	rr = httptest.NewRecorder()
+	SamplerHandler(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without ratio, got %d", rr.Code)
+	}
+}
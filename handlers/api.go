@@ -6,11 +6,16 @@ import (
 	"net/http"
 	"time"
 
+	"hello-world/middleware"
 	"hello-world/services"
 )
 
 var clickService = services.NewClickService()
 
+// farcasterPlatformHeader carries the client platform type ("web" or "mobile")
+// reported by the MiniApp SDK; see src/main.ts
+const farcasterPlatformHeader = "X-Farcaster-Platform"
+
 // ResetClickService resets the click counter for testing
 func ResetClickService() {
 	clickService.Reset()
@@ -18,6 +23,7 @@ func ResetClickService() {
 
 func TimeFragmentHandler(w http.ResponseWriter, r *http.Request) {
 	slog.InfoContext(r.Context(), "Time endpoint accessed")
+	middleware.SetCachePolicy(r, middleware.CachePolicy{NoStore: true})
 	currentTime := time.Now().Format("2006-01-02 15:04:05")
 
 	tmpl := `<div class="bg-blue-50 border border-blue-200 rounded-md p-4">
@@ -32,7 +38,7 @@ func TimeFragmentHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func ClickFragmentHandler(w http.ResponseWriter, r *http.Request) {
-	count := clickService.IncrementClick()
+	count := clickService.Click(r.Context(), clickAttributes(r))
 	slog.InfoContext(r.Context(), "Button clicked", "count", count)
 
 	tmpl := `<div class="bg-green-50 border border-green-200 rounded-md p-4">
@@ -46,6 +52,25 @@ func ClickFragmentHandler(w http.ResponseWriter, r *http.Request) {
 	t.Execute(w, data)
 }
 
+// clickAttributes describes the client of a click request for metrics. The
+// platform comes from the session's captured Farcaster context, falling back
+// to the header for sessions that have not reported one.
+func clickAttributes(r *http.Request) services.ClickAttributes {
+	platform := r.Header.Get(farcasterPlatformHeader)
+	if fc, ok := middleware.FarcasterContextFromContext(r.Context()); ok && fc.Client.PlatformType != "" {
+		platform = fc.Client.PlatformType
+	}
+	// Only an authenticated FID counts; the Mini App context is client-reported
+	_, authenticated := middleware.FIDFromContext(r.Context())
+	return services.ClickAttributes{
+		Platform:      platform,
+		Authenticated: authenticated,
+	}
+}
+
+// HealthcheckHandler reports that the process is up, like /livez. It stays
+// for existing probes; /readyz also checks dependencies and drain, and is what
+// the healthcheck command probes.
 func HealthcheckHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"hello-world/middleware"
+)
+
+// TimeJSONHandler returns the server time for API clients
+func TimeJSONHandler(w http.ResponseWriter, r *http.Request) {
+	middleware.SetCachePolicy(r, middleware.CachePolicy{NoStore: true})
+	writeJSON(w, http.StatusOK, map[string]string{"time": time.Now().UTC().Format(time.RFC3339)})
+}
+
+// ClicksJSONHandler returns the click count on GET and increments it on POST.
+// POST responses carry an HX-Trigger so HTMX front-ends can refresh counters.
+func ClicksJSONHandler(w http.ResponseWriter, r *http.Request) {
+	count := clickService.GetCount()
+	if r.Method == http.MethodPost {
+		count = clickService.Click(r.Context(), clickAttributes(r))
+		w.Header().Set("HX-Trigger", "clicksChanged")
+	}
+	middleware.SetCachePolicy(r, middleware.CachePolicy{NoStore: true})
+	writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
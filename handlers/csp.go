@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// cspViolation holds the fields shared by the report-uri and Reporting API formats
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	DocumentURL        string `json:"documentURL"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	BlockedURI         string `json:"blocked-uri"`
+	BlockedURL         string `json:"blockedURL"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+	Disposition        string `json:"disposition"`
+}
+
+// CSPReportHandler logs Content-Security-Policy violations sent by browsers.
+// It accepts the legacy application/csp-report body and Reporting API batches;
+// the body size is bounded by the route's MaxBodyMiddleware.
+func CSPReportHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	violations, err := parseCSPReports(body)
+	if err != nil {
+		slog.DebugContext(r.Context(), "Malformed CSP report", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, v := range violations {
+		slog.WarnContext(r.Context(), "CSP violation",
+			"document_uri", firstNonEmpty(v.DocumentURI, v.DocumentURL),
+			"violated_directive", firstNonEmpty(v.ViolatedDirective, v.EffectiveDirective),
+			"blocked_uri", firstNonEmpty(v.BlockedURI, v.BlockedURL),
+			"source_file", v.SourceFile,
+			"line_number", v.LineNumber,
+			"disposition", v.Disposition,
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseCSPReports decodes either {"csp-report": {...}} or [{"type": "csp-violation", "body": {...}}]
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	var legacy struct {
+		Report *cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &legacy); err == nil && legacy.Report != nil {
+		return []cspViolation{*legacy.Report}, nil
+	}
+
+	var batch []struct {
+		Type string       `json:"type"`
+		Body cspViolation `json:"body"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	var violations []cspViolation
+	for _, report := range batch {
+		if report.Type == "csp-violation" {
+			violations = append(violations, report.Body)
+		}
+	}
+	return violations, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSPReportHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"legacy report", `{"csp-report":{"document-uri":"https://app/","violated-directive":"script-src","blocked-uri":"inline"}}`, http.StatusNoContent},
+		{"reporting API batch", `[{"type":"csp-violation","body":{"documentURL":"https://app/","effectiveDirective":"script-src-elem","blockedURL":"https://evil.example/x.js"}}]`, http.StatusNoContent},
+		{"malformed", `not json`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/api/csp-report", strings.NewReader(test.body))
+		rr := httptest.NewRecorder()
+		CSPReportHandler(rr, req)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expectedStatus, rr.Code)
+		}
+	}
+}
+
+func TestParseCSPReports(t *testing.T) {
+	violations, err := parseCSPReports([]byte(`[{"type":"deprecation","body":{}},{"type":"csp-violation","body":{"blockedURL":"inline"}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].BlockedURL != "inline" {
+		t.Errorf("Expected only the CSP violation, got %+v", violations)
+	}
+}
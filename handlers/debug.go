@@ -1,27 +1,37 @@
 package handlers
 
 import (
-	"html/template"
 	"log/slog"
 	"net/http"
+
+	"hello-world/config"
 )
 
-func DebugHandler(w http.ResponseWriter, r *http.Request) {
-	slog.InfoContext(r.Context(), "Debug page accessed")
-	tmpl, err := template.ParseFiles(
-		"templates/layouts/base.html",
-		"templates/pages/debug.html",
-	)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+// DebugHandler renders the debug page. The server diagnostics panel is left
+// out when admin routes are served on a separate listener, as the page could
+// not reach them.
+func DebugHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "Debug page accessed")
+		tmpl, err := parsePage(r, "debug.html", "farcaster_context.html")
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
 
-	data := struct {
-		Title string
-	}{
-		Title: "Farcaster MiniApp Debug",
-	}
+		data := struct {
+			Title  string
+			Server farcasterContextData
+			// AdminListener reports that /admin routes are on the admin listener
+			AdminListener bool
+		}{
+			Title:         "Farcaster MiniApp Debug",
+			Server:        newFarcasterContextData(r),
+			AdminListener: cfg.Admin.Addr != "",
+		}
 
-	tmpl.Execute(w, data)
+		if err := tmpl.Execute(w, data); err == nil {
+			countPageView(r, "debug.html")
+		}
+	}
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"hello-world/config"
+	"hello-world/middleware"
+	"hello-world/services"
+)
+
+// diagnosticsData is rendered by the server-diagnostics component
+type diagnosticsData struct {
+	services.Diagnostics
+	Uptime         string
+	Settings       []config.Setting
+	Exporters      []middleware.ExporterStatus
+	TelemetryError middleware.TelemetryError
+	Headers        []headerRow
+}
+
+// DiagnosticsHandler renders the server diagnostics panel polled by /debug.
+// It is mounted on the admin subrouter, so only admins can reach it.
+func DiagnosticsHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		middleware.SetCachePolicy(r, middleware.CachePolicy{NoStore: true})
+		tmpl, err := parseComponent(r, "server_diagnostics.html")
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+
+		diagnostics := services.CollectDiagnostics(config.CommitHash, startedAt)
+		data := diagnosticsData{
+			Diagnostics:    diagnostics,
+			Uptime:         diagnostics.Runtime.Uptime.Round(time.Second).String(),
+			Settings:       cfg.Settings(),
+			Exporters:      middleware.ExporterStatuses(),
+			TelemetryError: middleware.LastTelemetryError(),
+			Headers:        requestHeaders(r),
+		}
+		tmpl.ExecuteTemplate(w, "server-diagnostics", data)
+	}
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "12.3 MiB"
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"hello-world/middleware"
+	"hello-world/models"
+)
+
+// errorPageData is rendered by the error page and the HTMX error fragment
+type errorPageData struct {
+	Title      string
+	Status     int
+	StatusText string
+	Message    string
+	RequestID  string
+}
+
+// NotFoundHandler renders the 404 page for unmatched routes
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, models.NewAppError(http.StatusNotFound, "The page you're looking for doesn't exist.", nil))
+}
+
+// MethodNotAllowedHandler renders the 405 page for routes matched with the wrong method
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, models.NewAppError(http.StatusMethodNotAllowed, "This action isn't supported here.", nil))
+}
+
+// ServerErrorHandler renders the 500 error page; it is used by the panic recovery middleware
+func ServerErrorHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, models.NewAppError(http.StatusInternalServerError, "", nil))
+}
+
+// ServiceUnavailableHandler renders the 503 page for requests that exceeded their timeout
+func ServiceUnavailableHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, models.NewAppError(http.StatusServiceUnavailable, "This is taking longer than expected. Please try again.", nil))
+}
+
+// RequestTooLargeHandler renders the 413 page for request bodies over the route limit
+func RequestTooLargeHandler(w http.ResponseWriter, r *http.Request) {
+	RenderError(w, r, models.NewAppError(http.StatusRequestEntityTooLarge, "That request is too large.", nil))
+}
+
+// RenderError writes err as an error page using the base layout, or as a
+// toast fragment for HTMX requests. Only an AppError's user-safe message is
+// shown; a body over the route limit becomes a 413, and any other error
+// becomes a generic 500 whose detail is only logged.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	var appErr *models.AppError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &maxBytesErr):
+		appErr = models.NewAppError(http.StatusRequestEntityTooLarge, "That request is too large.", err)
+	default:
+		appErr = models.NewAppError(http.StatusInternalServerError, "", err)
+	}
+	// The AppError may be a shared sentinel, so it is never modified
+	message := appErr.Message
+	if appErr.Status == http.StatusInternalServerError && message == http.StatusText(http.StatusInternalServerError) {
+		message = "Something went wrong on our side. Please try again."
+	}
+
+	ctx := r.Context()
+	if appErr.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "Request failed", "status", appErr.Status, "error", err)
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, message)
+	} else {
+		slog.InfoContext(ctx, "Request rejected", "status", appErr.Status, "error", err)
+	}
+
+	data := errorPageData{
+		Title:      http.StatusText(appErr.Status),
+		Status:     appErr.Status,
+		StatusText: http.StatusText(appErr.Status),
+		Message:    message,
+		RequestID:  middleware.RequestIDFromContext(ctx),
+	}
+
+	if middleware.IsHTMX(r) {
+		tmpl, err := parseComponent(r, "error_message.html")
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to parse error fragment", "error", err)
+			http.Error(w, data.StatusText, appErr.Status)
+			return
+		}
+		middleware.RetargetToast(w)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(appErr.Status)
+		tmpl.ExecuteTemplate(w, "error-message", data)
+		return
+	}
+
+	tmpl, err := parsePage(r, "error.html", "error_message.html")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to parse error page", "error", err)
+		http.Error(w, data.StatusText, appErr.Status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(appErr.Status)
+	tmpl.Execute(w, data)
+}
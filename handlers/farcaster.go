@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"hello-world/middleware"
+	"hello-world/models"
+	"hello-world/services"
+)
+
+// farcasterContexts holds the Mini App context reported by each session
+var farcasterContexts = services.NewFarcasterContextStore(services.DefaultSessionTTL)
+
+// debugRedactor hides credentials among the request headers shown on /debug
+var debugRedactor = middleware.NewRedactor(middleware.RedactorOptions{})
+
+// LookupFarcasterContext returns the Mini App context captured for a session,
+// for middleware.FarcasterContextMiddleware
+func LookupFarcasterContext(sessionID string) (*models.MiniAppContext, bool) {
+	return farcasterContexts.Get(sessionID)
+}
+
+// farcasterContextData is rendered by the farcaster-context component
+type farcasterContextData struct {
+	Context *models.MiniAppContext
+	Headers []headerRow
+}
+
+type headerRow struct {
+	Name  string
+	Value string
+}
+
+// FarcasterContextHandler captures the sdk.context posted by the debug page
+// (POST) and renders what the server holds for the session (GET)
+func FarcasterContextHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		captureFarcasterContext(w, r)
+		return
+	}
+
+	middleware.SetCachePolicy(r, middleware.CachePolicy{NoStore: true})
+	tmpl, err := parseComponent(r, "farcaster_context.html")
+	if err != nil {
+		RenderError(w, r, err)
+		return
+	}
+	tmpl.ExecuteTemplate(w, "farcaster-context", newFarcasterContextData(r))
+}
+
+func captureFarcasterContext(w http.ResponseWriter, r *http.Request) {
+	var fc models.MiniAppContext
+	if err := json.NewDecoder(r.Body).Decode(&fc); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if err := fc.Validate(); err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	sessionID := ""
+	if cookie, err := r.Cookie(middleware.SessionCookie); err == nil {
+		sessionID = cookie.Value
+	}
+	if !validSessionID(sessionID) {
+		sessionID = newSessionID()
+	}
+	// Reissued on every capture so the cookie lives as long as the stored context
+	setSessionCookie(w, r, sessionID)
+	farcasterContexts.Put(sessionID, &fc)
+
+	ctx := middleware.WithFarcasterContext(r.Context(), &fc)
+	slog.InfoContext(ctx, "Farcaster context captured")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newFarcasterContextData collects the session's context and the request
+// headers, with credentials redacted
+func newFarcasterContextData(r *http.Request) farcasterContextData {
+	data := farcasterContextData{Headers: requestHeaders(r)}
+	data.Context, _ = middleware.FarcasterContextFromContext(r.Context())
+	return data
+}
+
+// requestHeaders lists the request headers in name order with credentials redacted
+func requestHeaders(r *http.Request) []headerRow {
+	var rows []headerRow
+	for _, name := range slices.Sorted(maps.Keys(r.Header)) {
+		value := strings.Join(r.Header.Values(name), ", ")
+		value = debugRedactor.RedactValue("http.request.header."+strings.ToLower(name), value)
+		rows = append(rows, headerRow{Name: name, Value: value})
+	}
+	return rows
+}
+
+// newSessionID returns 128 random bits in hex
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func validSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// setSessionCookie issues the session cookie. Farcaster web clients embed the
+// app in a cross-site iframe, which requires SameSite=None and therefore HTTPS.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, sessionID string) {
+	secure := r.TLS != nil
+	if info, ok := middleware.ClientInfoFromContext(r.Context()); ok {
+		secure = info.Scheme == "https"
+	}
+	sameSite := http.SameSiteLaxMode
+	if secure {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     middleware.SessionCookie,
+		Value:    sessionID,
+		Path:     "/",
+		MaxAge:   int(services.DefaultSessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: sameSite,
+	})
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"hello-world/middleware"
+	"hello-world/models"
 )
 
 func TestTimeFragmentHandler(t *testing.T) {
@@ -128,6 +133,19 @@ func TestResetClickService(t *testing.T) {
 	}
 }
 
+func TestClickAttributesAuthenticated(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/click", nil)
+	req = req.WithContext(middleware.WithFarcasterContext(req.Context(), &models.MiniAppContext{User: models.MiniAppUser{FID: 3}}))
+	if attrs := clickAttributes(req); attrs.Authenticated {
+		t.Error("A Mini App context FID should not count as authenticated")
+	}
+
+	req = req.WithContext(middleware.ContextWithFID(req.Context(), 3))
+	if attrs := clickAttributes(req); !attrs.Authenticated {
+		t.Error("An authenticated FID should mark the click authenticated")
+	}
+}
+
 func TestHealthcheckHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/health", nil)
 	if err != nil {
@@ -156,3 +174,132 @@ func TestHealthcheckHandler(t *testing.T) {
 			contentType, expectedContentType)
 	}
 }
+
+func TestServerErrorHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(ServerErrorHandler)
+
+	handler.ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusInternalServerError)
+	}
+
+	if strings.Contains(rr.Body.String(), "templates/") {
+		t.Errorf("handler should not leak template paths: %s", rr.Body.String())
+	}
+}
+
+func TestRenderErrorLeavesAppErrorUnchanged(t *testing.T) {
+	sentinel := models.NewAppError(http.StatusInternalServerError, "", nil)
+	RenderError(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), sentinel)
+	if sentinel.Message != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("RenderError should not modify the caller's AppError, got message %q", sentinel.Message)
+	}
+}
+
+func TestSDKReadyHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"success", `{"success":true,"duration_ms":120.5,"platform":"mobile"}`, http.StatusNoContent},
+		{"failure", `{"success":false,"duration_ms":3000,"error":"timeout"}`, http.StatusNoContent},
+		{"invalid JSON", `{"success":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/sdk-ready", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			SDKReadyHandler(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("status = %d, want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSDKReadyHandlerTruncatesError(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	body := `{"success":false,"platform":"web","error":"` + strings.Repeat("x", 4*maxRUMMessageLen) + `"}`
+	rr := httptest.NewRecorder()
+	SDKReadyHandler(rr, httptest.NewRequest("POST", "/api/sdk-ready", strings.NewReader(body)))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if !strings.Contains(buf.String(), "MiniApp SDK failed") || strings.Contains(buf.String(), strings.Repeat("x", maxRUMMessageLen+1)) {
+		t.Errorf("Error should be logged truncated to %d bytes, got %d bytes of log", maxRUMMessageLen, buf.Len())
+	}
+}
+
+func TestRUMHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"web vitals", `{"traceparent":"00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01","page":"/","events":[{"type":"vital","name":"LCP","value":1800},{"type":"vital","name":"CLS","value":0.02}]}`, http.StatusNoContent},
+		{"errors and timings", `{"events":[{"type":"error","name":"error","message":"boom","line":3},{"type":"htmx","method":"POST","path":"/api/click","status":200,"duration_ms":42}]}`, http.StatusNoContent},
+		{"invalid events are dropped", `{"traceparent":"garbage","events":[{"type":"vital","name":"FID","value":10},{"type":"htmx","method":"TRACE"}]}`, http.StatusNoContent},
+		{"empty batch", `{"events":[]}`, http.StatusBadRequest},
+		{"oversized batch", `{"events":[` + strings.Repeat(`{"type":"vital","name":"CLS","value":0},`, 50) + `{"type":"vital","name":"CLS","value":0}]}`, http.StatusBadRequest},
+		{"invalid JSON", `{"events":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/rum", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+			rr := httptest.NewRecorder()
+			RUMHandler(rr, req)
+			if rr.Code != tt.want {
+				t.Errorf("status = %d, want %d", rr.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRUMHandlerLogsSanitizedURLs(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	page := "/" + strings.Repeat("a", 2*maxRUMMessageLen) + "?token=s3cret"
+	body := `{"page":"` + page + `","events":[{"type":"vital","name":"LCP","value":1800},{"type":"error","name":"error","message":"boom","source":"/app.js?token=s3cret"}]}`
+	rr := httptest.NewRecorder()
+	RUMHandler(rr, httptest.NewRequest("POST", "/api/rum", strings.NewReader(body)))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNoContent)
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("Query strings should be stripped, got %s", buf.String())
+	}
+	if strings.Contains(buf.String(), strings.Repeat("a", maxRUMMessageLen)) {
+		t.Errorf("Page should be truncated to %d bytes", maxRUMMessageLen)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		512:             "512 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+		3 << 30:         "3.0 GiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"hello-world/middleware"
+	"hello-world/services"
+)
+
+// telemetryErrorWindow is how long an OpenTelemetry SDK error marks telemetry unhealthy
+const telemetryErrorWindow = 2 * time.Minute
+
+// health answers the probes; main marks it started and draining
+var health = newHealthRegistry()
+
+func newHealthRegistry() *services.HealthRegistry {
+	h := services.NewHealthRegistry(services.DefaultHealthCacheTTL)
+	h.Register(services.HealthCheck{Name: "templates", Check: checkTemplates, Critical: true})
+	h.Register(services.HealthCheck{Name: "telemetry", Check: checkTelemetry})
+	return h
+}
+
+// Health returns the registry behind /readyz and /startupz, for registering
+// checks and reporting startup and drain
+func Health() *services.HealthRegistry {
+	return health
+}
+
+// checkTemplates parses every template, which pages read from disk per request
+func checkTemplates(context.Context) error {
+	_, err := template.New("health").Funcs(templateFuncs).ParseGlob("templates/*/*.html")
+	return err
+}
+
+// checkTelemetry fails while the OpenTelemetry SDK reports recent export errors
+func checkTelemetry(context.Context) error {
+	if last := middleware.LastTelemetryError(); last.Count > 0 && time.Since(last.Time) < telemetryErrorWindow {
+		return fmt.Errorf("%s ago: %s", time.Since(last.Time).Round(time.Second), last.Message)
+	}
+	return nil
+}
+
+// ProbeHandler answers a services.ProbeLive, ProbeReady or ProbeStartup probe
+// with a JSON report of each check, and 503 when the probe fails
+func ProbeHandler(probe string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := health.Probe(r.Context(), probe)
+		status := http.StatusOK
+		if report.Status == services.HealthFail {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, status, report)
+	}
+}
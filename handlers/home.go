@@ -1,25 +1,33 @@
 package handlers
 
 import (
-	"html/template"
 	"net/http"
+	"time"
+
+	"hello-world/config"
+	"hello-world/middleware"
 )
 
+// startedAt is the Last-Modified time of pages that only change between builds
+var startedAt = time.Now()
+
 func HomeHandler(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles(
-		"templates/layouts/base.html",
-		"templates/pages/home.html",
-	)
+	tmpl, err := parsePage(r, "home.html")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		RenderError(w, r, err)
 		return
 	}
 
+	// The page only changes with a new build
+	middleware.SetCachePolicy(r, middleware.CachePolicy{Version: config.CommitHash, LastModified: startedAt})
+
 	data := struct {
 		Title string
 	}{
 		Title: "HTMX + Go Demo",
 	}
 
-	tmpl.Execute(w, data)
+	if err := tmpl.Execute(w, data); err == nil {
+		countPageView(r, "home.html")
+	}
 }
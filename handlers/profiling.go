@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"hello-world/config"
+	"hello-world/services"
+)
+
+// Bounds of the ?seconds= parameter of ProfileCaptureHandler
+const (
+	defaultCaptureDuration = 10 * time.Second
+	maxCaptureDuration     = 2 * time.Minute
+)
+
+// ProfileCaptureHandler records CPU, heap and goroutine profiles and an
+// execution trace, and returns them as a .tar.gz download labelled with the
+// commit. ?seconds= sets the CPU profile and trace length and ?kinds= selects
+// a comma-separated subset of cpu, heap, goroutine and trace.
+func ProfileCaptureHandler(w http.ResponseWriter, r *http.Request) {
+	duration := defaultCaptureDuration
+	if s := r.URL.Query().Get("seconds"); s != "" {
+		seconds, err := strconv.Atoi(s)
+		if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > maxCaptureDuration {
+			writeJSONError(w, http.StatusBadRequest, "seconds must be between 1 and "+strconv.Itoa(int(maxCaptureDuration.Seconds())))
+			return
+		}
+		duration = time.Duration(seconds) * time.Second
+	}
+	var kinds []string
+	if k := r.URL.Query().Get("kinds"); k != "" {
+		kinds = strings.Split(k, ",")
+		for _, kind := range kinds {
+			if !slices.Contains(services.DefaultProfileKinds, kind) {
+				writeJSONError(w, http.StatusBadRequest, "kinds must be a subset of "+strings.Join(services.DefaultProfileKinds, ","))
+				return
+			}
+		}
+	}
+
+	// The capture outlives the server's WriteTimeout, so extend it for this response
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(duration + 30*time.Second)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(r.Context(), "Failed to extend write deadline for profile capture", "error", err)
+	}
+
+	slog.InfoContext(r.Context(), "Capturing profiles", "duration", duration.String(), "kinds", kinds)
+	set, err := services.CaptureProfiles(r.Context(), services.CaptureOptions{
+		Duration: duration,
+		Kinds:    kinds,
+		Commit:   config.CommitHash,
+	})
+	switch {
+	case errors.Is(err, services.ErrProfilerBusy):
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	case r.Context().Err() != nil:
+		// The client went away
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "Profile capture failed", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "profile capture failed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+set.ArchiveName()+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := set.WriteArchive(w); err != nil {
+		slog.WarnContext(r.Context(), "Failed to write profile archive", "error", err)
+	}
+}
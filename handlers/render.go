@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+
+	"hello-world/middleware"
+	"hello-world/services"
+)
+
+// templateFuncs declares the functions available to templates; request-specific
+// implementations are bound by requestFuncs before execution
+var templateFuncs = template.FuncMap{
+	"asset":       AssetURL,
+	"build":       BuildInfo,
+	"bytes":       formatBytes,
+	"cspNonce":    func() string { return "" },
+	"traceparent": func() string { return "" },
+}
+
+// requestFuncs returns template functions bound to r
+func requestFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"cspNonce":    func() string { return middleware.CSPNonce(r.Context()) },
+		"traceparent": func() string { return middleware.Traceparent(r.Context()) },
+	}
+}
+
+// parsePage parses the base layout with a page from templates/pages and any
+// shared components it uses from templates/components, bound to r
+func parsePage(r *http.Request, page string, components ...string) (*template.Template, error) {
+	files := []string{"templates/layouts/base.html", "templates/pages/" + page}
+	for _, component := range components {
+		files = append(files, "templates/components/"+component)
+	}
+	tmpl, err := template.New("base.html").Funcs(templateFuncs).ParseFiles(files...)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Funcs(requestFuncs(r)), nil
+}
+
+// countPageView counts a view of page once it is sent in full; revalidations
+// answered with 304 are not views
+func countPageView(r *http.Request, page string) {
+	ctx := r.Context()
+	middleware.OnFullResponse(r, func() { services.RecordPageView(ctx, page) })
+}
+
+// parseComponent parses a single HTMX fragment template from templates/components, bound to r
+func parseComponent(r *http.Request, component string) (*template.Template, error) {
+	tmpl, err := template.New(component).Funcs(templateFuncs).ParseFiles("templates/components/" + component)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Funcs(requestFuncs(r)), nil
+}
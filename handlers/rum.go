@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"hello-world/middleware"
+	"hello-world/services"
+)
+
+// Limits on RUM beacons; anything larger is a broken or hostile client
+const (
+	maxRUMEvents      = 50
+	maxRUMMessageLen  = 512
+	maxRUMVitalMillis = 10 * 60 * 1000
+	maxRUMLayoutShift = 100
+)
+
+// rumBeacon is a batch of browser measurements sent by src/main.ts
+type rumBeacon struct {
+	// Traceparent is the W3C traceparent rendered into the page by the server
+	Traceparent string     `json:"traceparent"`
+	Page        string     `json:"page"`
+	Platform    string     `json:"platform"`
+	Events      []rumEvent `json:"events"`
+}
+
+// rumEvent is a Web Vital ("vital"), an uncaught error ("error") or an HTMX request timing ("htmx")
+type rumEvent struct {
+	Type string `json:"type"`
+	// Name is the Web Vital name, or the error kind: "error" or "unhandledrejection"
+	Name string `json:"name"`
+	// Value is in milliseconds, or a score for CLS
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+	Source  string  `json:"source"`
+	Line    int     `json:"line"`
+	Column  int     `json:"column"`
+	Method  string  `json:"method"`
+	Path    string  `json:"path"`
+	Status  int     `json:"status"`
+	// DurationMS is the HTMX request duration in milliseconds
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// RUMHandler receives navigator.sendBeacon batches of Web Vitals, JavaScript
+// errors and HTMX timings. Measurements are recorded as metrics and log
+// records in the context of the page's server trace, and the beacon's own
+// span links to it. Invalid events are dropped; a malformed batch is a 400.
+func RUMHandler(w http.ResponseWriter, r *http.Request) {
+	// sendBeacon posts strings as text/plain, so the content type is not checked
+	var beacon rumBeacon
+	if err := json.NewDecoder(r.Body).Decode(&beacon); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if len(beacon.Events) == 0 || len(beacon.Events) > maxRUMEvents {
+		writeJSONError(w, http.StatusBadRequest, "a beacon carries 1 to 50 events")
+		return
+	}
+
+	ctx := r.Context()
+	carrier := propagation.MapCarrier{"traceparent": beacon.Traceparent}
+	if page := trace.SpanContextFromContext(propagation.TraceContext{}.Extract(ctx, carrier)); page.IsValid() {
+		trace.SpanFromContext(ctx).AddLink(trace.Link{SpanContext: page})
+		ctx = trace.ContextWithRemoteSpanContext(ctx, page)
+	}
+
+	// URLs lose their query strings, which can carry tokens
+	page := truncate(middleware.StripQuery(beacon.Page), maxRUMMessageLen)
+	dropped := 0
+	for _, event := range beacon.Events {
+		switch {
+		case event.Type == "vital" && validVital(event):
+			services.RecordWebVital(ctx, event.Name, event.Value, beacon.Platform)
+			slog.InfoContext(ctx, "Web Vital",
+				"name", event.Name,
+				"value", event.Value,
+				"rating", services.WebVitalRating(event.Name, event.Value),
+				"page", page,
+			)
+		case event.Type == "error" && (event.Name == "error" || event.Name == "unhandledrejection"):
+			services.RecordClientError(ctx, event.Name, beacon.Platform)
+			slog.WarnContext(ctx, "Client error",
+				"kind", event.Name,
+				"message", truncate(event.Message, maxRUMMessageLen),
+				"source", truncate(middleware.StripQuery(event.Source), maxRUMMessageLen),
+				"line", event.Line,
+				"column", event.Column,
+				"page", page,
+			)
+		case event.Type == "htmx" && validHTMXTiming(event):
+			duration := time.Duration(event.DurationMS * float64(time.Millisecond))
+			services.RecordHTMXTiming(ctx, event.Method, event.Status, duration)
+			slog.DebugContext(ctx, "HTMX request timing",
+				"method", event.Method,
+				"path", truncate(middleware.StripQuery(event.Path), maxRUMMessageLen),
+				"status", event.Status,
+				"duration_ms", event.DurationMS,
+			)
+		default:
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		slog.DebugContext(ctx, "Dropped invalid RUM events", "dropped", dropped, "received", len(beacon.Events))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func validVital(event rumEvent) bool {
+	if !services.IsWebVital(event.Name) || event.Value < 0 {
+		return false
+	}
+	if event.Name == services.WebVitalCLS {
+		return event.Value <= maxRUMLayoutShift
+	}
+	return event.Value <= maxRUMVitalMillis
+}
+
+func validHTMXTiming(event rumEvent) bool {
+	switch event.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	return event.Status >= 0 && event.Status < 600 &&
+		event.DurationMS >= 0 && event.DurationMS <= maxRUMVitalMillis
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
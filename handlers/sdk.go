@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"hello-world/services"
+)
+
+// sdkReadyReport is sent by the debug page once sdk.actions.ready() settles
+type sdkReadyReport struct {
+	Success    bool    `json:"success"`
+	DurationMS float64 `json:"duration_ms"`
+	Platform   string  `json:"platform"`
+	Error      string  `json:"error"`
+}
+
+// SDKReadyHandler records MiniApp SDK initialization outcomes reported by the debug page
+func SDKReadyHandler(w http.ResponseWriter, r *http.Request) {
+	var report sdkReadyReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	duration := time.Duration(report.DurationMS * float64(time.Millisecond))
+	services.RecordSDKReady(r.Context(), report.Platform, report.Success, duration)
+	if !report.Success {
+		slog.WarnContext(r.Context(), "MiniApp SDK failed to initialize",
+			"platform", services.NormalizePlatform(report.Platform),
+			"error", truncate(report.Error, maxRUMMessageLen),
+		)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
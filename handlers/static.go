@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"maps"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+
+	"hello-world/middleware"
+	"hello-world/services"
+	"hello-world/static"
+)
+
+// assetService fingerprints the embedded static files once at startup
+var assetService = mustLoadAssets()
+
+func mustLoadAssets() *services.AssetService {
+	assets, err := services.NewAssetService(static.FS)
+	if err != nil {
+		// The embedded FS is fixed at build time, so this is a build defect
+		panic("loading embedded static assets: " + err.Error())
+	}
+	return assets
+}
+
+// AssetURL returns the fingerprinted URL of a static file, e.g. "css/style.css"
+func AssetURL(name string) string {
+	return assetService.URL(name)
+}
+
+// StaticHandler serves embedded static files relative to /static/. Fingerprinted
+// names are cached forever; plain names must be revalidated. Precompressed .br
+// and .gz variants are sent to clients that accept them, and directories are
+// never listed.
+func StaticHandler(w http.ResponseWriter, r *http.Request) {
+	serveAsset(w, r, assetService)
+}
+
+func serveAsset(w http.ResponseWriter, r *http.Request, assets *services.AssetService) {
+	asset, hashed, ok := assets.Lookup(r.URL.Path)
+	if !ok {
+		NotFoundHandler(w, r)
+		return
+	}
+
+	h := w.Header()
+	if hashed {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	if contentType := mime.TypeByExtension(path.Ext(asset.Name)); contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+
+	content, etag := asset.Content, `"`+asset.Hash+`"`
+	if len(asset.Variants) > 0 {
+		h.Add("Vary", "Accept-Encoding")
+		encodings := slices.Sorted(maps.Keys(asset.Variants))
+		if encoding := middleware.NegotiateEncoding(r.Header.Get("Accept-Encoding"), encodings); encoding != "" {
+			content, etag = asset.Variants[encoding], `"`+asset.Hash+"-"+encoding+`"`
+			h.Set("Content-Encoding", encoding)
+		}
+	}
+	h.Set("ETag", etag)
+
+	// Embedded files have no modification time; ServeContent uses the ETag for conditional requests
+	http.ServeContent(w, r, asset.Name, time.Time{}, bytes.NewReader(content))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"hello-world/services"
+)
+
+func TestServeAsset(t *testing.T) {
+	assets, err := services.NewAssetService(fstest.MapFS{
+		"css/style.css":    {Data: []byte("body { color: red; }")},
+		"css/style.css.br": {Data: []byte("brotli")},
+		"js/main.js":       {Data: []byte("console.log('hi')")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	css, _, _ := assets.Lookup("css/style.css")
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		ifNoneMatch      string
+		expectedStatus   int
+		expectedCache    string
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{"hashed", css.HashedName, "", "", http.StatusOK, "public, max-age=31536000, immutable", "", "body { color: red; }"},
+		{"plain", "css/style.css", "", "", http.StatusOK, "no-cache", "", "body { color: red; }"},
+		{"precompressed", css.HashedName, "gzip, br", "", http.StatusOK, "public, max-age=31536000, immutable", "br", "brotli"},
+		{"variant not accepted", css.HashedName, "gzip", "", http.StatusOK, "public, max-age=31536000, immutable", "", "body { color: red; }"},
+		{"revalidated", "css/style.css", "", `"` + css.Hash + `"`, http.StatusNotModified, "no-cache", "", ""},
+		{"directory", "css/", "", "", http.StatusNotFound, "", "", ""},
+		{"missing", "css/missing.css", "", "", http.StatusNotFound, "", "", ""},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/"+test.path, nil)
+		req.URL.Path = test.path
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		if test.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		serveAsset(rr, req, assets)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expectedStatus, rr.Code)
+			continue
+		}
+		if rr.Code == http.StatusNotFound {
+			continue
+		}
+		if got := rr.Header().Get("Cache-Control"); got != test.expectedCache {
+			t.Errorf("%s: expected Cache-Control %q, got %q", test.name, test.expectedCache, got)
+		}
+		if got := rr.Header().Get("Content-Encoding"); got != test.expectedEncoding {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", test.name, test.expectedEncoding, got)
+		}
+		if got := rr.Body.String(); got != test.expectedBody {
+			t.Errorf("%s: expected body %q, got %q", test.name, test.expectedBody, got)
+		}
+	}
+}
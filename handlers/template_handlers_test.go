@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"hello-world/config"
 )
 
 func TestHomeHandlerWithTemplates(t *testing.T) {
@@ -40,7 +42,7 @@ func TestDebugHandlerWithTemplates(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(DebugHandler)
+	handler := DebugHandler(&config.Config{})
 
 	handler.ServeHTTP(rr, req)
 
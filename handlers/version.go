@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"hello-world/config"
+	"hello-world/middleware"
+	"hello-world/services"
+)
+
+// buildInfo is read once since the binary cannot change
+var buildInfo = sync.OnceValue(func() services.BuildInfo {
+	return services.ReadBuildInfo(config.CommitHash, config.BuildTime)
+})
+
+// BuildInfo describes the running binary for /version, the page footer and the build_info metric
+func BuildInfo() services.BuildInfo {
+	return buildInfo()
+}
+
+// VersionHandler reports the commit, build time, Go version, dirty flag and
+// module dependencies of the running binary
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	// The response only changes with a new build
+	middleware.SetCachePolicy(r, middleware.CachePolicy{Version: config.CommitHash, LastModified: startedAt})
+	writeJSON(w, http.StatusOK, BuildInfo())
+}
@@ -3,18 +3,21 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"syscall"
 	"time"
 
 	"hello-world/config"
+	"hello-world/handlers"
 	"hello-world/middleware"
 	"hello-world/routes"
+	"hello-world/services"
 
 	"go.opentelemetry.io/contrib/bridges/otelslog"
 	"go.opentelemetry.io/otel"
@@ -30,99 +33,232 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-// CommitHash is set at build time via ldflags
-var CommitHash = "unknown"
-
 func main() {
-	// Temporary console logger before OTEL init
-	tempLogger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
-	slog.SetDefault(tempLogger)
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// serve runs the server until a shutdown signal; reload provides the
+// configuration applied on SIGHUP
+func serve(cfg *config.Config, reload func() (*config.Config, error)) {
+
+	// Console logger before OTEL init; it stays as one sink once OTEL logging is enabled
+	applyLogLevel(middleware.RootLogger, cfg.Log.Level)
+	applyLogLevel(middleware.ConsoleSink, cfg.Log.ConsoleLevel)
+	applyLogLevel(middleware.OTelSink, cfg.Log.OTelLevel)
+	redactor := middleware.NewRedactor(middleware.RedactorOptions{Keys: cfg.Log.RedactKeys})
+	consoleSink := middleware.NewLogSink(middleware.ConsoleSink, newConsoleHandler(cfg.Log.ConsoleFormat), cfg.Log.ConsoleDropAttrs)
+	slog.SetDefault(newLogger(consoleSink, redactor))
 
 	ctx := context.Background()
+	otlpEndpoint := cfg.Telemetry.Endpoint
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(middleware.RecordTelemetryError))
+	// Flushed in order by the last shutdown phase
+	var telemetryShutdown []func(context.Context) error
 
 	// Initialize tracing
-	traceShutdown, err := initOtelTracing(ctx)
+	traceShutdown, err := initOtelTracing(ctx, cfg.Telemetry, redactor)
+	middleware.SetExporterStatus("traces", otlpEndpoint, err)
 	if err != nil {
 		slog.Warn("OpenTelemetry tracing not enabled", "error", err)
 	} else {
 		slog.Info("OpenTelemetry tracing enabled")
-		defer func() {
-			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			_ = traceShutdown(flushCtx)
-		}()
+		telemetryShutdown = append(telemetryShutdown, traceShutdown)
 	}
 
 	// Initialize metrics
-	metricsShutdown, err := initOtelMetrics(ctx)
+	metricsShutdown, err := initOtelMetrics(ctx, cfg.Telemetry)
+	middleware.SetExporterStatus("metrics", otlpEndpoint, err)
 	if err != nil {
 		slog.Warn("OpenTelemetry metrics not enabled", "error", err)
 	} else {
 		slog.Info("OpenTelemetry metrics enabled")
-		defer func() {
-			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			_ = metricsShutdown(flushCtx)
-		}()
+		telemetryShutdown = append(telemetryShutdown, metricsShutdown)
 	}
 
+	// Labels metrics with the running build for deployment tracking
+	services.RegisterBuildInfoMetric(handlers.BuildInfo())
+
 	// Initialize logging with trace integration
-	logShutdown, err := initOtelLogging(ctx)
+	logShutdown, err := initOtelLogging(ctx, cfg.Telemetry, consoleSink, cfg.Log.OTelDropAttrs, redactor)
+	middleware.SetExporterStatus("logs", otlpEndpoint, err)
 	if err != nil {
 		slog.Warn("OpenTelemetry logging not enabled", "error", err)
 	} else {
 		slog.Info("OpenTelemetry logging enabled")
-		defer func() {
-			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			_ = logShutdown(flushCtx)
-		}()
+		telemetryShutdown = append(telemetryShutdown, logShutdown)
 	}
 
-	// Load configuration
-	cfg := config.Load()
+	// Continuous profiling into a local directory, stopped on shutdown
+	profilerCtx, stopProfiler := context.WithCancel(ctx)
+	defer stopProfiler()
+	if cfg.Profile.Dir != "" {
+		handlers.Health().Register(services.HealthCheck{Name: "profile_dir", Check: func(context.Context) error {
+			return checkWritableDir(cfg.Profile.Dir)
+		}})
+		profiler := services.NewContinuousProfiler(services.ContinuousProfilerOptions{
+			Dir:         cfg.Profile.Dir,
+			Interval:    cfg.Profile.Interval,
+			CPUDuration: cfg.Profile.CPUDuration,
+			Retention:   cfg.Profile.Retention,
+			Commit:      config.CommitHash,
+		})
+		go func() {
+			if err := profiler.Run(profilerCtx); err != nil {
+				slog.Warn("Continuous profiling not enabled", "error", err)
+			}
+		}()
+	}
 
 	// Setup routes and HTTP server
-	r := routes.SetupRoutes()
+	r := routes.NewRouter(cfg)
 	srv := &http.Server{
-		Addr:         ":" + cfg.Port,
+		Addr:         ":" + cfg.Server.Port,
 		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	// Optional admin listener for health, profiling and runtime settings. It has
+	// no write timeout because CPU profiles and traces stream for their duration.
+	var adminSrv *http.Server
+	if cfg.Admin.Addr != "" {
+		adminSrv = &http.Server{
+			Addr:        cfg.Admin.Addr,
+			Handler:     routes.NewAdminRouter(cfg),
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+	}
+
+	// Bind both listeners before reporting startup so /startupz only passes
+	// once connections are accepted; without the admin listener the probes
+	// would be unreachable, so failing to bind either is fatal
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		slog.Error("HTTP server error", "error", err)
+		os.Exit(1)
+	}
+	var adminLn net.Listener
+	if adminSrv != nil {
+		if adminLn, err = net.Listen("tcp", adminSrv.Addr); err != nil {
+			slog.Error("Admin server error", "error", err)
+			os.Exit(1)
+		}
 	}
 
 	go func() {
-		slog.Info("Server starting", "url", "http://localhost:"+cfg.Port, "commit", CommitHash)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Info("Server starting", "url", "http://localhost:"+cfg.Server.Port, "commit", config.CommitHash)
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
 			slog.Error("HTTP server error", "error", err)
 		}
 	}()
+	if adminSrv != nil {
+		go func() {
+			slog.Info("Admin server starting", "addr", cfg.Admin.Addr)
+			if err := adminSrv.Serve(adminLn); err != nil && err != http.ErrServerClosed {
+				slog.Error("Admin server error", "error", err)
+			}
+		}()
+	}
 
-	// Graceful shutdown on SIGINT/SIGTERM
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
-	<-stop
-	slog.Info("Shutdown signal received")
+	handlers.Health().MarkStarted()
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(shutdownCtx); err != nil {
-		slog.Error("Graceful shutdown failed", "error", err)
+	// Reload runtime settings on SIGHUP, graceful shutdown on SIGINT/SIGTERM
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	var sig os.Signal
+	for sig = range signals {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		slog.Info("Reload signal received")
+		next, err := reload()
+		if err != nil {
+			slog.Error("Keeping the current settings, the configuration is invalid", "error", err)
+			continue
+		}
+		reloadRuntimeSettings(next)
 	}
+	slog.Info("Shutdown signal received", "signal", sig.String())
+
+	// A second signal skips the rest of the drain
+	go func() {
+		for sig := range signals {
+			if sig != syscall.SIGHUP {
+				slog.Warn("Second shutdown signal received, exiting immediately", "signal", sig.String())
+				os.Exit(1)
+			}
+		}
+	}()
+
+	shutdownDone := make(chan error, 1)
+	services.RunShutdown(ctx, []services.ShutdownPhase{
+		{Name: "fail_readiness", Run: func(context.Context) error {
+			handlers.Health().SetDraining(true)
+			return nil
+		}},
+		{Name: "pre_stop_delay", Budget: cfg.Drain.PreStopDelay, Run: func(ctx context.Context) error {
+			// Keep serving while load balancers notice the failing readiness probe
+			<-ctx.Done()
+			return nil
+		}},
+		{Name: "stop_accepting", Run: func(context.Context) error {
+			// Shutdown closes the listener at once, then waits for connections to go idle
+			go func() { shutdownDone <- srv.Shutdown(context.Background()) }()
+			return nil
+		}},
+		{Name: "notify_streams", Run: func(context.Context) error {
+			middleware.StartDrain()
+			stopProfiler()
+			return nil
+		}},
+		{Name: "wait_in_flight", Budget: cfg.Drain.Timeout, Run: func(ctx context.Context) error {
+			err := middleware.WaitForInFlight(ctx)
+			if err == nil {
+				select {
+				case err = <-shutdownDone:
+				case <-ctx.Done():
+					err = ctx.Err()
+				}
+			}
+			if err != nil {
+				inFlight := middleware.InFlightRequests()
+				srv.Close()
+				return fmt.Errorf("closed %d requests still in flight: %w", inFlight, err)
+			}
+			return nil
+		}},
+		// The admin server stays up until public traffic has drained; profiles
+		// still being captured are cut short
+		{Name: "stop_admin", Budget: time.Second, Run: func(ctx context.Context) error {
+			if adminSrv == nil {
+				return nil
+			}
+			if err := adminSrv.Shutdown(ctx); err != nil {
+				adminSrv.Close()
+				return err
+			}
+			return nil
+		}},
+		{Name: "flush_telemetry", Budget: cfg.Drain.FlushTimeout, Run: func(ctx context.Context) error {
+			var errs []error
+			for _, shutdown := range telemetryShutdown {
+				errs = append(errs, shutdown(ctx))
+			}
+			return errors.Join(errs...)
+		}},
+	})
 }
 
-// initOtelLogging initializes an OTLP HTTP exporter and slog bridge.
-func initOtelLogging(ctx context.Context) (func(context.Context) error, error) {
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT is not set")
+// initOtelLogging initializes an OTLP HTTP exporter and slog bridge, fanned out alongside the console sink.
+func initOtelLogging(ctx context.Context, telemetry config.TelemetryConfig, consoleSink slog.Handler, dropAttrs []string, redactor *middleware.Redactor) (func(context.Context) error, error) {
+	if telemetry.Endpoint == "" {
+		return nil, errTelemetryDisabled
 	}
 
-	// TLS/insecure config via env
-	insecure := otlpInsecure()
-	logOpts := []otlploghttp.Option{otlploghttp.WithEndpoint(endpoint)}
-	if insecure {
+	logOpts := []otlploghttp.Option{otlploghttp.WithEndpoint(telemetry.Endpoint)}
+	if telemetry.Insecure {
 		logOpts = append(logOpts, otlploghttp.WithInsecure())
 	} else {
 		logOpts = append(logOpts, otlploghttp.WithTLSClientConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
@@ -138,7 +274,7 @@ func initOtelLogging(ctx context.Context) (func(context.Context) error, error) {
 		resource.WithTelemetrySDK(),
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String("hello-world"),
-			semconv.ServiceVersionKey.String(CommitHash),
+			semconv.ServiceVersionKey.String(config.CommitHash),
 		),
 	)
 	if err != nil {
@@ -157,25 +293,22 @@ func initOtelLogging(ctx context.Context) (func(context.Context) error, error) {
 		otelslog.WithLoggerProvider(provider),
 	)
 
-	// Wrap with TraceHandler to add trace IDs to logs
-	traceHandler := middleware.NewTraceHandler(otelSlog.Handler())
-	tracedLogger := slog.New(traceHandler)
-	slog.SetDefault(tracedLogger)
+	// Write to both the console and OTEL sinks so container logs keep working
+	otelSink := middleware.NewLogSink(middleware.OTelSink, otelSlog.Handler(), dropAttrs)
+	slog.SetDefault(newLogger(middleware.NewFanoutHandler(consoleSink, otelSink), redactor))
 
 	return provider.Shutdown, nil
 }
 
-// initOtelTracing initializes OpenTelemetry tracing
-func initOtelTracing(ctx context.Context) (func(context.Context) error, error) {
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT is not set")
+// initOtelTracing initializes OpenTelemetry tracing, redacting span attributes before export
+func initOtelTracing(ctx context.Context, telemetry config.TelemetryConfig, redactor *middleware.Redactor) (func(context.Context) error, error) {
+	if telemetry.Endpoint == "" {
+		return nil, errTelemetryDisabled
 	}
 
 	// Create trace exporter
-	insecure := otlpInsecure()
-	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
-	if insecure {
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(telemetry.Endpoint)}
+	if telemetry.Insecure {
 		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
 	} else {
 		traceOpts = append(traceOpts, otlptracehttp.WithTLSClientConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
@@ -191,7 +324,7 @@ func initOtelTracing(ctx context.Context) (func(context.Context) error, error) {
 		resource.WithTelemetrySDK(),
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String("hello-world"),
-			semconv.ServiceVersionKey.String(CommitHash),
+			semconv.ServiceVersionKey.String(config.CommitHash),
 		),
 	)
 	if err != nil {
@@ -200,9 +333,9 @@ func initOtelTracing(ctx context.Context) (func(context.Context) error, error) {
 
 	// Create trace provider
 	traceProvider := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(traceExporter),
+		sdktrace.WithSpanProcessor(middleware.NewRedactingSpanProcessor(sdktrace.NewBatchSpanProcessor(traceExporter), redactor)),
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(envOrDefaultSampler()),
+		sdktrace.WithSampler(configuredSampler(telemetry.SampleRatio)),
 	)
 
 	// Set global trace provider and composite propagator
@@ -218,19 +351,17 @@ func initOtelTracing(ctx context.Context) (func(context.Context) error, error) {
 }
 
 // initOtelMetrics initializes OpenTelemetry metrics
-func initOtelMetrics(ctx context.Context) (func(context.Context) error, error) {
-	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
-	if endpoint == "" {
-		return nil, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT is not set")
+func initOtelMetrics(ctx context.Context, telemetry config.TelemetryConfig) (func(context.Context) error, error) {
+	if telemetry.Endpoint == "" {
+		return nil, errTelemetryDisabled
 	}
 
 	// Create metrics exporter
-	insecure := otlpInsecure()
 	metricOpts := []otlpmetrichttp.Option{
-		otlpmetrichttp.WithEndpoint(endpoint),
+		otlpmetrichttp.WithEndpoint(telemetry.Endpoint),
 		otlpmetrichttp.WithURLPath("/v1/metrics"),
 	}
-	if insecure {
+	if telemetry.Insecure {
 		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
 	} else {
 		metricOpts = append(metricOpts, otlpmetrichttp.WithTLSClientConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
@@ -246,7 +377,7 @@ func initOtelMetrics(ctx context.Context) (func(context.Context) error, error) {
 		resource.WithTelemetrySDK(),
 		resource.WithAttributes(
 			semconv.ServiceNameKey.String("hello-world"),
-			semconv.ServiceVersionKey.String(CommitHash),
+			semconv.ServiceVersionKey.String(config.CommitHash),
 		),
 	)
 	if err != nil {
@@ -256,7 +387,7 @@ func initOtelMetrics(ctx context.Context) (func(context.Context) error, error) {
 	// Create metrics provider
 	reader := sdkmetric.NewPeriodicReader(
 		metricsExporter,
-		sdkmetric.WithInterval(30*time.Second), // Export every 30 seconds
+		sdkmetric.WithInterval(telemetry.MetricsInterval),
 	)
 
 	metricsProvider := sdkmetric.NewMeterProvider(
@@ -270,31 +401,62 @@ func initOtelMetrics(ctx context.Context) (func(context.Context) error, error) {
 	return metricsProvider.Shutdown, nil
 }
 
-// otlpInsecure returns whether to use insecure transport to the OTLP endpoint. Defaults to true.
-func otlpInsecure() bool {
-	v := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE")
-	if v == "" {
-		return true
+// errTelemetryDisabled is reported for each signal when no OTLP endpoint is configured
+var errTelemetryDisabled = errors.New("telemetry.endpoint (OTEL_EXPORTER_OTLP_ENDPOINT) is not set")
+
+// configuredSampler returns the runtime-adjustable sampler seeded with the configured ratio
+func configuredSampler(ratio float64) sdktrace.Sampler {
+	sampler := middleware.TraceSampler()
+	sampler.SetRatio(ratio)
+	return sampler
+}
+
+// newConsoleHandler creates the stdout handler in the configured format. Its own level is
+// left open so the adjustable sink and logger levels decide what is written.
+func newConsoleHandler(format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == "json" {
+		return slog.NewJSONHandler(os.Stdout, opts)
 	}
-	b, err := strconv.ParseBool(v)
+	return slog.NewTextHandler(os.Stdout, opts)
+}
+
+// newLogger wraps sink with redaction, trace enrichment and the adjustable root level.
+func newLogger(sink slog.Handler, redactor *middleware.Redactor) *slog.Logger {
+	return slog.New(middleware.NewLevelHandler(
+		middleware.LogLevel(middleware.RootLogger),
+		middleware.NewTraceHandler(redactor.Handler(sink)),
+	))
+}
+
+// applyLogLevel sets a logger or sink level from its configured name, keeping the current level if invalid.
+func applyLogLevel(logger, name string) {
+	level, err := middleware.ParseLevel(name)
 	if err != nil {
-		return true
+		slog.Warn("Ignoring log level", "logger", logger, "error", err)
+		return
 	}
-	return b
+	middleware.SetLogLevel(logger, level)
 }
 
-// envOrDefaultSampler selects a sampler from env vars or defaults to ParentBased(10%).
-func envOrDefaultSampler() sdktrace.Sampler {
-	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
-		if ratio, err := strconv.ParseFloat(arg, 64); err == nil {
-			if ratio < 0 {
-				ratio = 0
-			}
-			if ratio > 1 {
-				ratio = 1
-			}
-			return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))
-		}
+// checkWritableDir fails unless a file can be created in dir
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// reloadRuntimeSettings restores the configured root log level and sampling ratio,
+// discarding overrides made through the admin endpoints.
+func reloadRuntimeSettings(cfg *config.Config) {
+	ctx := context.Background()
+	if level, err := middleware.ParseLevel(cfg.Log.Level); err != nil {
+		slog.Warn("Ignoring log level", "error", err)
+	} else {
+		middleware.ChangeLogLevel(ctx, middleware.RootLogger, level, "sighup")
 	}
-	return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	middleware.ChangeSampleRatio(ctx, cfg.Telemetry.SampleRatio, "sighup")
 }
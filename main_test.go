@@ -6,6 +6,9 @@ import (
 	"strings"
 	"testing"
 
+	"go.opentelemetry.io/otel/trace"
+
+	"hello-world/config"
 	"hello-world/handlers"
 	"hello-world/models"
 	"hello-world/routes"
@@ -96,6 +99,23 @@ func TestClickHandler(t *testing.T) {
 	}
 }
 
+func TestDebugPageWithAdminListener(t *testing.T) {
+	tests := []struct {
+		adminAddr string
+		polls     bool
+	}{
+		{"", true},
+		{"127.0.0.1:9090", false},
+	}
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		handlers.DebugHandler(&config.Config{Admin: config.AdminConfig{Addr: test.adminAddr}}).ServeHTTP(rr, httptest.NewRequest("GET", "/debug", nil))
+		if polls := strings.Contains(rr.Body.String(), `hx-get="/admin/diagnostics"`); polls != test.polls {
+			t.Errorf("admin addr %q: expected diagnostics polling %v, got %v", test.adminAddr, test.polls, polls)
+		}
+	}
+}
+
 func TestDebugHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/debug", nil)
 	if err != nil {
@@ -103,7 +123,7 @@ func TestDebugHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.DebugHandler)
+	handler := handlers.DebugHandler(&config.Config{})
 
 	handler.ServeHTTP(rr, req)
 
@@ -173,3 +193,332 @@ func TestPageDataStruct(t *testing.T) {
 		t.Errorf("Expected Time to be '2023-01-01 12:00:00', got %s", data.Time)
 	}
 }
+
+func TestServerErrorPage(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	handlers.ServerErrorHandler(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "Something went wrong") || !strings.Contains(body, "<html>") {
+		t.Errorf("Expected full error page using the base layout, got %q", body)
+	}
+
+	// HTMX requests get only the fragment
+	req.Header.Set("HX-Request", "true")
+	rr = httptest.NewRecorder()
+	handlers.ServerErrorHandler(rr, req)
+	if strings.Contains(rr.Body.String(), "<html>") || !strings.Contains(rr.Body.String(), "Something went wrong") {
+		t.Errorf("Expected error fragment for HTMX request, got %q", rr.Body.String())
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	tests := []struct {
+		method   string
+		path     string
+		htmx     bool
+		status   int
+		contains string
+	}{
+		{"GET", "/nonexistent", false, http.StatusNotFound, "doesn&#39;t exist"},
+		{"POST", "/", false, http.StatusMethodNotAllowed, "isn&#39;t supported"},
+		{"GET", "/nonexistent", true, http.StatusNotFound, "404 · Not Found"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		if rr.Code != test.status {
+			t.Errorf("%s %s: expected status %d, got %d", test.method, test.path, test.status, rr.Code)
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, test.contains) {
+			t.Errorf("%s %s: expected body to contain %q, got %q", test.method, test.path, test.contains, body)
+		}
+		if test.htmx {
+			if strings.Contains(body, "<html>") {
+				t.Errorf("%s %s: HTMX request should get a fragment", test.method, test.path)
+			}
+			if rr.Header().Get("HX-Retarget") != "#toast-region" {
+				t.Errorf("%s %s: HTMX error should target the toast region", test.method, test.path)
+			}
+		} else if !strings.Contains(body, "toast-region") {
+			t.Errorf("%s %s: error page should use the base layout", test.method, test.path)
+		}
+	}
+}
+
+func TestPageScriptsCarryCSPNonce(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	for _, path := range []string{"/", "/debug"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+
+		csp := rr.Header().Get("Content-Security-Policy")
+		start := strings.Index(csp, "'nonce-")
+		if start < 0 {
+			t.Fatalf("%s: CSP header should contain a nonce, got %q", path, csp)
+		}
+		nonce := strings.SplitN(csp[start+len("'nonce-"):], "'", 2)[0]
+
+		body := rr.Body.String()
+		scripts := strings.Count(body, "<script") - strings.Count(body, "<script src=")
+		if got := strings.Count(body, `nonce="`+nonce+`"`); got == 0 || got != scripts {
+			t.Errorf("%s: expected every inline script to carry the nonce, got %d of %d", path, got, scripts)
+		}
+	}
+}
+
+func TestStaticAssetsCompressed(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	req := httptest.NewRequest("GET", "/static/css/style.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected gzip-encoded stylesheet, got %q", rr.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(rr.Header().Get("Vary"), "Accept-Encoding") {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", rr.Header().Get("Vary"))
+	}
+}
+
+func TestFingerprintedAssets(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	stylesheet := handlers.AssetURL("css/style.css")
+	if stylesheet == "/static/css/style.css" {
+		t.Fatal("Expected a fingerprinted stylesheet URL")
+	}
+	if !strings.Contains(rr.Body.String(), `href="`+stylesheet+`"`) {
+		t.Errorf("Home page should link %s", stylesheet)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", stylesheet, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for %s, got %d", stylesheet, rr.Code)
+	}
+	if !strings.Contains(rr.Header().Get("Cache-Control"), "immutable") {
+		t.Errorf("Fingerprinted assets should be immutable, got %q", rr.Header().Get("Cache-Control"))
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/static/css/", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Directory listings should be disabled, got status %d", rr.Code)
+	}
+}
+
+func TestConditionalHomePage(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Home page should carry an ETag")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/api/time", nil))
+	if rr.Header().Get("Cache-Control") != "no-store" || rr.Header().Get("ETag") != "" {
+		t.Errorf("Time fragment should never be cached, got Cache-Control %q", rr.Header().Get("Cache-Control"))
+	}
+}
+
+func TestRequestLimitErrors(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	req := httptest.NewRequest("POST", "/api/click", strings.NewReader(strings.Repeat("x", 4096)))
+	req.Header.Set("HX-Request", "true")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+	if rr.Header().Get("HX-Retarget") != "#toast-region" || !strings.Contains(rr.Body.String(), "too large") {
+		t.Errorf("Oversized HTMX request should get an error toast, got %q", rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	handlers.ServiceUnavailableHandler(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusServiceUnavailable || !strings.Contains(rr.Body.String(), "longer than expected") {
+		t.Errorf("Expected 503 timeout page, got %d", rr.Code)
+	}
+}
+
+func TestPageRendersTraceparent(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(trace.ContextWithSpanContext(req.Context(), sc))
+	rr := httptest.NewRecorder()
+	handlers.HomeHandler(rr, req)
+
+	want := `<meta name="traceparent" content="00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01">`
+	if !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("Expected the page to render its traceparent for RUM correlation")
+	}
+}
+
+func TestRUMBeaconRoute(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	body := `{"page":"/","events":[{"type":"vital","name":"TTFB","value":120}]}`
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("POST", "/api/rum", strings.NewReader(body)))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a valid beacon, got %d", rr.Code)
+	}
+}
+
+func TestFarcasterContextCapture(t *testing.T) {
+	r := routes.SetupRoutes()
+
+	invalid := httptest.NewRecorder()
+	r.ServeHTTP(invalid, httptest.NewRequest("POST", "/api/farcaster-context", strings.NewReader(`{"user":{"fid":0}}`)))
+	if invalid.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an invalid context, got %d", invalid.Code)
+	}
+
+	body := `{"user":{"fid":3,"username":"dwr"},"client":{"platformType":"mobile","clientFid":9152,"added":true},"location":{"type":"launcher"}}`
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("POST", "/api/farcaster-context", strings.NewReader(body)))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly {
+		t.Fatalf("Expected an HttpOnly session cookie, got %v", cookies)
+	}
+
+	req := httptest.NewRequest("GET", "/debug", nil)
+	req.AddCookie(cookies[0])
+	req.Header.Set("Authorization", "Bearer secret-token")
+	page := httptest.NewRecorder()
+	r.ServeHTTP(page, req)
+
+	html := page.Body.String()
+	if !strings.Contains(html, "@dwr") || !strings.Contains(html, "launcher") {
+		t.Error("Expected the captured context in the server-side section of /debug")
+	}
+	if strings.Contains(html, "secret-token") {
+		t.Error("Credentials must be redacted from the request headers shown on /debug")
+	}
+}
+
+func TestServerDiagnosticsRequireAdmin(t *testing.T) {
+	cfg := config.Load()
+	cfg.Admin.Token = "diagnostics-token"
+	r := routes.NewRouter(cfg)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest("GET", "/admin/diagnostics", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without the admin token, got %d", rr.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/diagnostics", nil)
+	req.Header.Set("Authorization", "Bearer diagnostics-token")
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an admin, got %d", rr.Code)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"Goroutines", config.CommitHash, "admin.token", config.Masked} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected diagnostics to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "diagnostics-token") {
+		t.Error("The admin token must not be shown in diagnostics")
+	}
+	if rr.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Diagnostics must not be cached, got %q", rr.Header().Get("Cache-Control"))
+	}
+}
+
+func TestProbeRoutes(t *testing.T) {
+	router := routes.NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}})
+	health := handlers.Health()
+	health.MarkStarted()
+	t.Cleanup(func() { health.SetDraining(false) })
+
+	probe := func(path string) (int, string) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr.Code, rr.Body.String()
+	}
+
+	for _, path := range []string{"/livez", "/readyz", "/startupz"} {
+		if code, body := probe(path); code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", path, code, body)
+		}
+	}
+	if _, body := probe("/readyz"); !strings.Contains(body, `"name":"templates"`) {
+		t.Errorf("Readiness should report each check, got %s", body)
+	}
+
+	health.SetDraining(true)
+	if code, body := probe("/readyz"); code != http.StatusServiceUnavailable || !strings.Contains(body, `"reason":"draining"`) {
+		t.Errorf("Readiness should fail while draining, got %d: %s", code, body)
+	}
+	if code, _ := probe("/livez"); code != http.StatusOK {
+		t.Errorf("Liveness should pass while draining, got %d", code)
+	}
+}
+
+func TestVersionEndpointAndFooter(t *testing.T) {
+	router := routes.NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/version", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a JSON version report, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	for _, field := range []string{`"commit":`, `"build_time":`, `"go_version":`, `"dirty":`, `"dependencies":`} {
+		if !strings.Contains(rr.Body.String(), field) {
+			t.Errorf("Version report should contain %s, got %s", field, rr.Body.String())
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	build := handlers.BuildInfo()
+	if body := rr.Body.String(); !strings.Contains(body, "<code>"+build.Commit+"</code>") || !strings.Contains(body, build.GoVersion) {
+		t.Errorf("Footer should show the commit and Go version")
+	}
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessLogger is the name of the logger access log records are written to
+const AccessLogger = "access"
+
+// Access log formats
+const (
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+	AccessLogOff      = "off"
+)
+
+// AccessLogOptions configures AccessLogMiddleware
+type AccessLogOptions struct {
+	// Format is AccessLogCombined, AccessLogJSON or AccessLogOff
+	Format string
+	// SampleRate is the fraction of fast 2xx and 3xx responses that are logged
+	SampleRate float64
+	// SlowThreshold marks requests that are always logged; zero disables it
+	SlowThreshold time.Duration
+	// Redactor scrubs the request line and referer of combined lines; JSON
+	// records are redacted by the slog handler chain. request_id and trace_id
+	// are added to both formats by TraceHandler from the request context.
+	Redactor *Redactor
+}
+
+// AccessLogMiddleware emits one log record per request. Client and server
+// errors and requests slower than SlowThreshold are always logged, other
+// requests are sampled at SampleRate.
+func AccessLogMiddleware(opts AccessLogOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if opts.Format == AccessLogOff || opts.Format == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := &ObservabilityResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(wrapped, r)
+
+			duration := time.Since(start)
+			slow := opts.SlowThreshold > 0 && duration >= opts.SlowThreshold
+			failed := wrapped.statusCode >= http.StatusInternalServerError
+			rejected := wrapped.statusCode >= http.StatusBadRequest
+			if !slow && !rejected && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case failed:
+				level = slog.LevelError
+			case slow:
+				level = slog.LevelWarn
+			}
+
+			entry := newAccessLogEntry(r, wrapped, start, duration)
+			logger := Logger(AccessLogger)
+			if opts.Format == AccessLogCombined {
+				logger.Log(r.Context(), level, entry.combined(opts.Redactor))
+				return
+			}
+			logger.LogAttrs(r.Context(), level, "HTTP request", entry.attrs(slow)...)
+		})
+	}
+}
+
+// accessLogEntry holds the fields of one access log record
+type accessLogEntry struct {
+	start     time.Time
+	method    string
+	uri       string
+	proto     string
+	route     string
+	status    int
+	bytes     int64
+	duration  time.Duration
+	remoteIP  string
+	referer   string
+	userAgent string
+}
+
+func newAccessLogEntry(r *http.Request, w *ObservabilityResponseWriter, start time.Time, duration time.Duration) accessLogEntry {
+	route := ""
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			route = tmpl
+		}
+	}
+
+	return accessLogEntry{
+		start:     start,
+		method:    r.Method,
+		uri:       r.URL.RequestURI(),
+		proto:     r.Proto,
+		route:     route,
+		status:    w.statusCode,
+		bytes:     w.responseSize,
+		duration:  duration,
+		remoteIP:  ClientIP(r),
+		referer:   r.Referer(),
+		userAgent: r.UserAgent(),
+	}
+}
+
+// combined formats the entry as an Apache Combined Log Format line
+func (e accessLogEntry) combined(redactor *Redactor) string {
+	uri, referer := e.uri, e.referer
+	if redactor != nil {
+		uri = redactor.RedactValue("url.full", uri)
+		referer = redactor.RedactValue("referer", referer)
+	}
+
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+
+	return fmt.Sprintf("%s - - [%s] %q %d %s %q %q",
+		orDash(e.remoteIP),
+		e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		e.method+" "+uri+" "+e.proto,
+		e.status,
+		size,
+		orDash(referer),
+		orDash(e.userAgent),
+	)
+}
+
+// attrs returns the entry as structured attributes
+func (e accessLogEntry) attrs(slow bool) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("http.request.method", e.method),
+		slog.String("http.route", e.route),
+		slog.String("url.full", e.uri),
+		slog.Int("http.response.status_code", e.status),
+		slog.Int64("http.response.body.size", e.bytes),
+		slog.Float64("duration_ms", float64(e.duration.Microseconds())/1000),
+		slog.String("client.address", e.remoteIP),
+		slog.String("referer", e.referer),
+		slog.String("user_agent.original", e.userAgent),
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	return attrs
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
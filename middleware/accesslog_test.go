@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureLogs routes the default logger into a JSON buffer for the duration of a test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(NewTraceHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func statusHandler(status int, delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(status)
+		w.Write([]byte("hello"))
+	})
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	buf := captureLogs(t)
+	handler := AccessLogMiddleware(AccessLogOptions{Format: AccessLogJSON, SampleRate: 1})(statusHandler(http.StatusOK, 0))
+
+	req := httptest.NewRequest("GET", "/api/time?x=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "https://example.com/")
+	req = req.WithContext(ContextWithRequestID(req.Context(), "req-1"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON record, got %q: %v", buf.String(), err)
+	}
+	expected := map[string]any{
+		"logger":                    AccessLogger,
+		"http.request.method":       "GET",
+		"http.response.status_code": float64(200),
+		"http.response.body.size":   float64(5),
+		"client.address":            "192.0.2.1",
+		"user_agent.original":       "test-agent",
+		"referer":                   "https://example.com/",
+		"request_id":                "req-1",
+	}
+	for key, want := range expected {
+		if record[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, record[key])
+		}
+	}
+}
+
+func TestAccessLogCombined(t *testing.T) {
+	buf := captureLogs(t)
+	opts := AccessLogOptions{Format: AccessLogCombined, SampleRate: 1, Redactor: NewRedactor(RedactorOptions{})}
+	handler := AccessLogMiddleware(opts)(statusHandler(http.StatusNotFound, 0))
+
+	req := httptest.NewRequest("GET", "/missing?token=abc", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := record["msg"].(string)
+	if !strings.HasPrefix(msg, "192.0.2.1 - - [") {
+		t.Errorf("Unexpected combined line prefix: %q", msg)
+	}
+	if !strings.Contains(msg, `"GET /missing HTTP/1.1" 404 5 "-" "test-agent"`) {
+		t.Errorf("Unexpected combined line: %q", msg)
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		delay  time.Duration
+		logged bool
+		level  string
+	}{
+		{"sampled out success", http.StatusOK, 0, false, ""},
+		{"client error always logged", http.StatusTooManyRequests, 0, true, "INFO"},
+		{"server error always logged", http.StatusInternalServerError, 0, true, "ERROR"},
+		{"slow request always logged", http.StatusOK, 20 * time.Millisecond, true, "WARN"},
+	}
+
+	for _, test := range tests {
+		buf := captureLogs(t)
+		opts := AccessLogOptions{Format: AccessLogJSON, SampleRate: 0, SlowThreshold: 10 * time.Millisecond}
+		handler := AccessLogMiddleware(opts)(statusHandler(test.status, test.delay))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		if logged := buf.Len() > 0; logged != test.logged {
+			t.Errorf("%s: expected logged=%v, got %q", test.name, test.logged, buf.String())
+			continue
+		}
+		if test.logged && !strings.Contains(buf.String(), `"level":"`+test.level+`"`) {
+			t.Errorf("%s: expected level %s, got %q", test.name, test.level, buf.String())
+		}
+	}
+}
+
+func TestAccessLogOff(t *testing.T) {
+	buf := captureLogs(t)
+	handler := AccessLogMiddleware(AccessLogOptions{Format: AccessLogOff, SampleRate: 1})(statusHandler(http.StatusOK, 0))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("Access log should be disabled, got %q", buf.String())
+	}
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ClientInfo describes the originating client of a request
+type ClientInfo struct {
+	// Address is the resolved client IP
+	Address string
+	// Scheme is "http" or "https" as seen by the client
+	Scheme string
+	// PeerAddress is the IP of the directly connected peer, usually a proxy
+	PeerAddress string
+}
+
+// ParseTrustedProxies parses CIDRs or bare IPs into prefixes, reporting every invalid entry
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	var errs []error
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("trusted proxy %q: %w", v, err))
+				continue
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trusted proxy %q: %w", v, err))
+			continue
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, errors.Join(errs...)
+}
+
+// ClientIPMiddleware resolves the client address and scheme, honouring
+// Forwarded, X-Forwarded-For/Proto and X-Real-IP only when the connection
+// comes from one of the trusted proxies. The result is stored in the request
+// context and recorded on the active span.
+func ClientIPMiddleware(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := ResolveClientInfo(r, trusted)
+			trace.SpanFromContext(r.Context()).SetAttributes(clientAttributes(info)...)
+			next.ServeHTTP(w, r.WithContext(ContextWithClientInfo(r.Context(), info)))
+		})
+	}
+}
+
+// ResolveClientInfo determines the client address and scheme of r
+func ResolveClientInfo(r *http.Request, trusted []netip.Prefix) ClientInfo {
+	peer := remoteIP(r)
+	info := ClientInfo{Address: peer, Scheme: "http", PeerAddress: peer}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+	if !isTrusted(peer, trusted) {
+		return info
+	}
+
+	hops, protos := forwardedHops(r)
+	if len(hops) == 0 {
+		return info
+	}
+
+	// Walk from the nearest hop back towards the client, stopping at the
+	// first address not belonging to a trusted proxy
+	chosen := -1
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(hops[i]); err != nil {
+			break
+		}
+		chosen = i
+		if !isTrusted(hops[i], trusted) {
+			break
+		}
+	}
+	if chosen < 0 {
+		return info
+	}
+
+	info.Address = hops[chosen]
+	if chosen < len(protos) && protos[chosen] != "" {
+		info.Scheme = protos[chosen]
+	} else if len(protos) > 0 && protos[0] != "" {
+		info.Scheme = protos[0]
+	}
+	return info
+}
+
+// forwardedHops returns the client-first list of forwarded addresses and their protocols
+func forwardedHops(r *http.Request) (hops, protos []string) {
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			var hop, proto string
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				value = strings.Trim(value, `"`)
+				switch strings.ToLower(key) {
+				case "for":
+					hop = stripPort(value)
+				case "proto":
+					proto = strings.ToLower(value)
+				}
+			}
+			hops = append(hops, hop)
+			protos = append(protos, proto)
+		}
+		return hops, protos
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		for _, hop := range strings.Split(strings.Join(xff, ","), ",") {
+			hops = append(hops, stripPort(strings.TrimSpace(hop)))
+		}
+		for _, proto := range strings.Split(r.Header.Get("X-Forwarded-Proto"), ",") {
+			protos = append(protos, strings.ToLower(strings.TrimSpace(proto)))
+		}
+		return hops, protos
+	}
+
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return []string{stripPort(strings.TrimSpace(realIP))}, []string{strings.ToLower(r.Header.Get("X-Forwarded-Proto"))}
+	}
+	return nil, nil
+}
+
+// remoteIP returns the host part of the connection's remote address
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// stripPort removes an optional port and IPv6 brackets from a forwarded address
+func stripPort(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func clientAttributes(info ClientInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.ClientAddressKey.String(info.Address),
+		semconv.NetworkPeerAddressKey.String(info.PeerAddress),
+		semconv.URLSchemeKey.String(info.Scheme),
+	}
+}
+
+// ContextWithClientInfo adds client information to the context
+func ContextWithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey, info)
+}
+
+// ClientInfoFromContext returns the client information stored by ClientIPMiddleware
+func ClientInfoFromContext(ctx context.Context) (ClientInfo, bool) {
+	info, ok := ctx.Value(clientInfoKey).(ClientInfo)
+	return info, ok
+}
+
+// ClientIP returns the resolved client IP of r, falling back to the connection's remote address
+func ClientIP(r *http.Request) string {
+	if info, ok := ClientInfoFromContext(r.Context()); ok {
+		return info.Address
+	}
+	return remoteIP(r)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.7", "::1", "bogus", "10.0.0.0/99"})
+	if err == nil {
+		t.Error("Expected an error for invalid entries")
+	}
+	if len(prefixes) != 3 {
+		t.Fatalf("Expected 3 valid prefixes, got %v", prefixes)
+	}
+	if prefixes[1].String() != "192.168.1.7/32" {
+		t.Errorf("Expected bare IP to become a /32, got %s", prefixes[1])
+	}
+}
+
+func TestResolveClientInfo(t *testing.T) {
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8", "fd00::/8"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		tls        bool
+		wantAddr   string
+		wantScheme string
+	}{
+		{"direct connection", "203.0.113.5:1234", nil, false, "203.0.113.5", "http"},
+		{"untrusted peer headers ignored", "203.0.113.5:1234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"}, false, "203.0.113.5", "http"},
+		{"direct TLS", "203.0.113.5:1234", nil, true, "203.0.113.5", "https"},
+		{"trusted proxy X-Forwarded-For", "10.0.0.2:80",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https"}, false, "198.51.100.1", "https"},
+		{"spoofed left-most entry skipped", "10.0.0.2:80",
+			map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 10.0.0.3"}, false, "198.51.100.1", "http"},
+		{"all hops trusted", "10.0.0.2:80",
+			map[string]string{"X-Forwarded-For": "10.0.0.9, 10.0.0.3"}, false, "10.0.0.9", "http"},
+		{"Forwarded header", "10.0.0.2:80",
+			map[string]string{"Forwarded": `for=198.51.100.1;proto=https, for="[fd00::1]:4711"`}, false, "198.51.100.1", "https"},
+		{"Forwarded IPv6 client", "10.0.0.2:80",
+			map[string]string{"Forwarded": `for="[2001:db8::1]:4711";proto=https`}, false, "2001:db8::1", "https"},
+		{"Forwarded obfuscated", "10.0.0.2:80",
+			map[string]string{"Forwarded": `for=_hidden`}, false, "10.0.0.2", "http"},
+		{"X-Real-IP", "10.0.0.2:80",
+			map[string]string{"X-Real-IP": "198.51.100.7"}, false, "198.51.100.7", "http"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remoteAddr
+		for key, value := range test.headers {
+			req.Header.Set(key, value)
+		}
+		if test.tls {
+			req.TLS = &tls.ConnectionState{}
+		}
+
+		info := ResolveClientInfo(req, trusted)
+		if info.Address != test.wantAddr {
+			t.Errorf("%s: expected address %s, got %s", test.name, test.wantAddr, info.Address)
+		}
+		if info.Scheme != test.wantScheme {
+			t.Errorf("%s: expected scheme %s, got %s", test.name, test.wantScheme, info.Scheme)
+		}
+		if info.PeerAddress != remoteIP(req) {
+			t.Errorf("%s: expected peer address %s, got %s", test.name, remoteIP(req), info.PeerAddress)
+		}
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	trusted, _ := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	var got string
+	handler := ClientIPMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = ClientIP(r)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "198.51.100.1" {
+		t.Errorf("Expected client IP from context, got %s", got)
+	}
+
+	// Without the middleware the connection address is used
+	if ip := ClientIP(req); ip != "10.1.2.3" {
+		t.Errorf("Expected fallback to remote address, got %s", ip)
+	}
+}
@@ -0,0 +1,328 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by CompressionMiddleware
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// DefaultCompressionMinSize is the smallest body worth compressing
+const DefaultCompressionMinSize = 1024
+
+// DefaultCompressionEncodings lists the supported codings in server preference order
+var DefaultCompressionEncodings = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// DefaultCompressibleTypes lists the media types compressed by default
+var DefaultCompressibleTypes = []string{
+	"text/html",
+	"text/css",
+	"text/plain",
+	"text/javascript",
+	"text/event-stream",
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// CompressionOptions configures CompressionMiddleware
+type CompressionOptions struct {
+	// MinSize is the body size below which responses are sent uncompressed;
+	// defaults to DefaultCompressionMinSize. Flushed (streaming) responses are
+	// compressed regardless of size.
+	MinSize int
+	// Encodings lists the enabled codings in preference order; defaults to DefaultCompressionEncodings
+	Encodings []string
+	// ContentTypes lists the compressible media types; defaults to DefaultCompressibleTypes
+	ContentTypes []string
+}
+
+// encoder is the common interface of the pooled gzip, brotli and zstd writers
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encoderPools holds one pool of reusable encoders per coding
+var encoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}},
+	EncodingBrotli: {New: func() any {
+		return brotli.NewWriterLevel(io.Discard, brotli.DefaultCompression)
+	}},
+	EncodingZstd: {New: func() any {
+		enc, err := zstd.NewWriter(io.Discard,
+			zstd.WithEncoderLevel(zstd.SpeedDefault),
+			zstd.WithEncoderConcurrency(1),
+			zstd.WithLowerEncoderMem(true),
+		)
+		if err != nil {
+			// Only reachable with invalid options
+			panic(err)
+		}
+		return enc
+	}},
+}
+
+// CompressionMiddleware compresses responses with the best coding accepted by
+// the client. Bodies are buffered until MinSize bytes are written so small
+// responses are sent as-is; responses with a Content-Encoding, a type outside
+// ContentTypes, or no body semantics (HEAD, 204, 206, 304) pass through.
+// Flush pushes compressed data to the client immediately for SSE.
+func CompressionMiddleware(opts CompressionOptions) func(http.Handler) http.Handler {
+	if opts.MinSize <= 0 {
+		opts.MinSize = DefaultCompressionMinSize
+	}
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = DefaultCompressionEncodings
+	}
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = DefaultCompressibleTypes
+	}
+	opts.Encodings = slices.DeleteFunc(slices.Clone(opts.Encodings), func(e string) bool {
+		if _, ok := encoderPools[e]; !ok {
+			slog.Warn("Ignoring unsupported compression encoding", "encoding", e)
+			return true
+		}
+		return false
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressResponseWriter{
+				ResponseWriter: w,
+				opts:           &opts,
+				encoding:       NegotiateEncoding(r.Header.Get("Accept-Encoding"), opts.Encodings),
+				head:           r.Method == http.MethodHead,
+				status:         http.StatusOK,
+			}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// NegotiateEncoding picks the accepted coding with the highest q-value,
+// breaking ties by server preference. It returns "" for identity.
+func NegotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qvalues := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if key, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(key) == "q" {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		qvalues[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supported {
+		q, ok := qvalues[encoding]
+		if !ok {
+			q, ok = qvalues["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressResponseWriter buffers the start of the body to decide whether to
+// compress, then streams through a pooled encoder
+type compressResponseWriter struct {
+	http.ResponseWriter
+	opts     *CompressionOptions
+	encoding string
+	head     bool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+func (w *compressResponseWriter) WriteHeader(code int) {
+	// Informational responses go straight through
+	if code < http.StatusOK {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.opts.MinSize && !w.knownLarge() {
+			return len(b), nil
+		}
+		if err := w.start(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.write(b)
+}
+
+// knownLarge reports whether a declared Content-Length already exceeds MinSize
+func (w *compressResponseWriter) knownLarge() bool {
+	length, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	return err == nil && length >= w.opts.MinSize
+}
+
+// Flush compresses what has been written so far and pushes it to the client
+func (w *compressResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.start(true); err != nil {
+			return
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return
+		}
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// start writes the headers, choosing compression when allowed and worthwhile,
+// then sends the buffered body
+func (w *compressResponseWriter) start(worthwhile bool) error {
+	w.decided = true
+	h := w.Header()
+
+	compressible := h.Get("Content-Encoding") == "" && w.bodyAllowed() && w.compressibleType()
+	if compressible {
+		addVary(h, "Accept-Encoding")
+	}
+	if compressible && worthwhile && w.encoding != "" && !w.head {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		// The compressed representation is no longer byte-identical
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		w.enc = encoderPools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+// write sends b through the encoder, if any
+func (w *compressResponseWriter) write(b []byte) (int, error) {
+	if w.enc == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	n, err := w.enc.Write(b)
+	recordUncompressed(w.ResponseWriter, int64(n))
+	return n, err
+}
+
+// close finishes the response once the handler returns
+func (w *compressResponseWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader {
+			// Nothing written: let net/http send its implicit empty 200
+			return
+		}
+		if err := w.start(len(w.buf) >= w.opts.MinSize); err != nil {
+			return
+		}
+	}
+	if w.enc == nil {
+		return
+	}
+	if err := w.enc.Close(); err != nil {
+		slog.Warn("Failed to finish compressed response", "encoding", w.encoding, "error", err)
+	}
+	w.enc.Reset(io.Discard)
+	encoderPools[w.encoding].Put(w.enc)
+	w.enc = nil
+}
+
+// bodyAllowed reports whether the status carries a full representation
+func (w *compressResponseWriter) bodyAllowed() bool {
+	switch w.status {
+	case http.StatusNoContent, http.StatusPartialContent, http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// compressibleType sniffs a missing Content-Type the way net/http would and
+// checks it against the allowlist
+func (w *compressResponseWriter) compressibleType() bool {
+	contentType := w.Header().Get("Content-Type")
+	if contentType == "" {
+		if len(w.buf) == 0 {
+			return false
+		}
+		contentType = http.DetectContentType(w.buf)
+		w.Header().Set("Content-Type", contentType)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(w.opts.ContentTypes, mediaType)
+}
+
+// addVary appends value to the Vary header unless already present
+func addVary(h http.Header, value string) {
+	for _, existing := range h.Values("Vary") {
+		for _, v := range strings.Split(existing, ",") {
+			if v = strings.TrimSpace(v); v == "*" || strings.EqualFold(v, value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
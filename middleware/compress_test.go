@@ -0,0 +1,248 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", EncodingGzip},
+		{"gzip, deflate, br", EncodingBrotli},
+		{"gzip, deflate, br, zstd", EncodingZstd},
+		{"zstd;q=0.5, gzip", EncodingGzip},
+		{"br;q=0, gzip;q=0.1", EncodingGzip},
+		{"*", EncodingZstd},
+		{"*;q=0.5, zstd;q=0", EncodingBrotli},
+		{"GZIP", EncodingGzip},
+	}
+
+	for _, test := range tests {
+		if got := NegotiateEncoding(test.acceptEncoding, DefaultCompressionEncodings); got != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.acceptEncoding, test.expected, got)
+		}
+	}
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	switch encoding {
+	case EncodingGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(body))
+	case EncodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		r = bytes.NewReader(body)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", encoding, err)
+	}
+	return string(decoded)
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("<p>Hello, Farcaster!</p>\n", 100)
+	html := func(body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, body)
+		})
+	}
+
+	tests := []struct {
+		name             string
+		handler          http.Handler
+		acceptEncoding   string
+		expectedEncoding string
+		expectedVary     bool
+	}{
+		{"gzip", html(large), "gzip", EncodingGzip, true},
+		{"brotli", html(large), "gzip, br", EncodingBrotli, true},
+		{"zstd", html(large), "gzip, br, zstd", EncodingZstd, true},
+		{"not accepted", html(large), "", "", true},
+		{"below minimum size", html("<p>small</p>"), "gzip", "", true},
+		{"sniffed content type", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, large)
+		}), "gzip", EncodingGzip, true},
+		{"incompressible type", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, large)
+		}), "gzip", "", false},
+		{"already encoded", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/css")
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, large)
+		}), "gzip", "br", false},
+	}
+
+	for _, test := range tests {
+		handler := CompressionMiddleware(CompressionOptions{})(test.handler)
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		encoding := rr.Header().Get("Content-Encoding")
+		if encoding != test.expectedEncoding {
+			t.Errorf("%s: expected Content-Encoding %q, got %q", test.name, test.expectedEncoding, encoding)
+			continue
+		}
+		if vary := rr.Header().Get("Vary") == "Accept-Encoding"; vary != test.expectedVary {
+			t.Errorf("%s: expected Vary on Accept-Encoding to be %v, got %q", test.name, test.expectedVary, rr.Header().Get("Vary"))
+		}
+		if encoding != "" && !test.expectedVary {
+			// Pre-encoded bodies are passed through untouched
+			continue
+		}
+		if body := decode(t, encoding, rr.Body.Bytes()); body != large && body != "<p>small</p>" {
+			t.Errorf("%s: body did not round-trip", test.name)
+		}
+	}
+}
+
+func TestCompressionMiddlewarePreservesStatusAndETag(t *testing.T) {
+	handler := CompressionMiddleware(CompressionOptions{MinSize: 10})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "28")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"message":"created object"}`)
+	}))
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Length") != "" {
+		t.Error("Content-Length of the uncompressed body must be removed")
+	}
+	if rr.Header().Get("ETag") != `W/"abc"` {
+		t.Errorf("Expected weakened ETag, got %q", rr.Header().Get("ETag"))
+	}
+	if body := decode(t, EncodingGzip, rr.Body.Bytes()); body != `{"message":"created object"}` {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestCompressionMiddlewareSkipsBodilessResponses(t *testing.T) {
+	handler := CompressionMiddleware(CompressionOptions{MinSize: 1})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified || rr.Header().Get("Content-Encoding") != "" || rr.Body.Len() != 0 {
+		t.Errorf("Expected untouched 304, got %d %q with %d bytes", rr.Code, rr.Header().Get("Content-Encoding"), rr.Body.Len())
+	}
+}
+
+func TestCompressionMiddlewareFlushesEvents(t *testing.T) {
+	flushed := make(chan string, 1)
+	handler := CompressionMiddleware(CompressionOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: tick\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush failed: %v", err)
+		}
+		flushed <- w.Header().Get("Content-Encoding")
+	}))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	rec := &flushRecorder{ResponseRecorder: rr}
+	handler.ServeHTTP(rec, req)
+
+	if encoding := <-flushed; encoding != EncodingGzip {
+		t.Fatalf("Expected streaming response to be compressed, got %q", encoding)
+	}
+	if len(rec.atFlush) == 0 {
+		t.Fatal("Expected compressed bytes to reach the client on Flush")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(rec.atFlush))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := make([]byte, len("data: tick\n\n"))
+	if _, err := io.ReadFull(zr, event); err != nil || string(event) != "data: tick\n\n" {
+		t.Errorf("Expected flushed event, got %q (%v)", event, err)
+	}
+}
+
+// flushRecorder snapshots the body written when Flush is called
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	atFlush []byte
+}
+
+func (r *flushRecorder) Flush() {
+	r.atFlush = bytes.Clone(r.Body.Bytes())
+	r.ResponseRecorder.Flush()
+}
+
+func TestCompressionRecordsUncompressedSize(t *testing.T) {
+	large := strings.Repeat("a", 4096)
+	handler := CompressionMiddleware(CompressionOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, large)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	// An outer writer as installed by AccessLogMiddleware, wrapped by ObservabilityMiddleware's
+	outer := &ObservabilityResponseWriter{ResponseWriter: rr, statusCode: http.StatusOK}
+	inner := &ObservabilityResponseWriter{ResponseWriter: outer, statusCode: http.StatusOK}
+	handler.ServeHTTP(inner, req)
+
+	for name, w := range map[string]*ObservabilityResponseWriter{"outer": outer, "inner": inner} {
+		if w.UncompressedSize() != int64(len(large)) {
+			t.Errorf("%s: expected uncompressed size %d, got %d", name, len(large), w.UncompressedSize())
+		}
+		if w.responseSize != int64(rr.Body.Len()) || w.responseSize >= int64(len(large)) {
+			t.Errorf("%s: expected wire size %d, got %d", name, rr.Body.Len(), w.responseSize)
+		}
+	}
+
+	plain := &ObservabilityResponseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
+	plain.Write([]byte("hello"))
+	if plain.UncompressedSize() != 5 {
+		t.Errorf("Uncompressed size should equal wire size without compression, got %d", plain.UncompressedSize())
+	}
+}
@@ -0,0 +1,267 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	httpCacheRequests metric.Int64Counter
+	cacheMetricOnce   sync.Once
+)
+
+// Cache results recorded on the http.server.cache.requests counter
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// cacheResultKey records whether a cacheable response was answered with 304
+const cacheResultKey = attribute.Key("http.cache.result")
+
+// CachePolicy declares how clients may cache a response
+type CachePolicy struct {
+	// NoStore forbids caching; the response is streamed without validators
+	NoStore bool
+	// Version is mixed into the ETag, e.g. the build commit hash
+	Version string
+	// LastModified is sent as Last-Modified and compared with If-Modified-Since; zero omits it
+	LastModified time.Time
+	// MaxAge lets clients reuse the response without revalidating; zero revalidates every time
+	MaxAge time.Duration
+}
+
+// cacheDeclaration is the mutable slot handlers fill through SetCachePolicy.
+// onFull holds the OnFullResponse callbacks of a buffered response.
+type cacheDeclaration struct {
+	policy    *CachePolicy
+	buffering bool
+	onFull    []func()
+}
+
+// SetCachePolicy declares the cacheability of the response to r. It must be
+// called before the first write and has no effect outside ConditionalGetMiddleware.
+func SetCachePolicy(r *http.Request, policy CachePolicy) {
+	if decl, ok := r.Context().Value(cachePolicyKey).(*cacheDeclaration); ok {
+		decl.policy = &policy
+	}
+}
+
+// OnFullResponse runs fn once the response to r is sent in full. It runs at
+// once unless ConditionalGetMiddleware is buffering the response, and never
+// if the response is then answered with 304 Not Modified, so call it after
+// the body is written.
+func OnFullResponse(r *http.Request, fn func()) {
+	if decl, ok := r.Context().Value(cachePolicyKey).(*cacheDeclaration); ok && decl.buffering {
+		decl.onFull = append(decl.onFull, fn)
+		return
+	}
+	fn()
+}
+
+// ConditionalGetMiddleware adds validators to GET and HEAD responses whose
+// handlers declared a CachePolicy. Cacheable 200 responses are buffered to
+// compute a strong ETag over the body (excluding the per-request CSP nonce),
+// and If-None-Match/If-Modified-Since are answered with 304 Not Modified.
+// Undeclared responses pass through untouched.
+func ConditionalGetMiddleware(next http.Handler) http.Handler {
+	cacheMetricOnce.Do(func() {
+		var err error
+		httpCacheRequests, err = observabilityMeter.Int64Counter(
+			"http.server.cache.requests",
+			metric.WithDescription("Conditional requests for cacheable responses by result; hit ratio is hit / (hit + miss)"),
+			metric.WithUnit("{request}"),
+		)
+		if err != nil {
+			slog.Warn("Failed to initialize cache metric", "error", err)
+		}
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decl := &cacheDeclaration{}
+		r = r.WithContext(context.WithValue(r.Context(), cachePolicyKey, decl))
+		cw := &conditionalResponseWriter{ResponseWriter: w, decl: decl, status: http.StatusOK}
+
+		next.ServeHTTP(cw, r)
+
+		if cw.buffering {
+			cw.finish(r)
+		}
+	})
+}
+
+// conditionalResponseWriter decides on the first write whether to stream or
+// to buffer for validation
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	decl      *cacheDeclaration
+	decided   bool
+	buffering bool
+	status    int
+	buf       bytes.Buffer
+}
+
+func (w *conditionalResponseWriter) decide(status int) {
+	w.decided = true
+	w.status = status
+	policy := w.decl.policy
+	switch {
+	case policy == nil:
+	case policy.NoStore:
+		w.Header().Set("Cache-Control", "no-store")
+	case status == http.StatusOK:
+		w.buffering = true
+		w.decl.buffering = true
+	}
+}
+
+func (w *conditionalResponseWriter) WriteHeader(code int) {
+	if code < http.StatusOK || w.decided {
+		if !w.buffering {
+			w.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	w.decide(code)
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *conditionalResponseWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		return w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush is a no-op while buffering; cacheable responses are sent whole
+func (w *conditionalResponseWriter) Flush() {
+	if !w.buffering {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *conditionalResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// finish validates the buffered response and sends either a 304 or the full body
+func (w *conditionalResponseWriter) finish(r *http.Request) {
+	policy := w.decl.policy
+	h := w.Header()
+
+	etag := computeETag(policy.Version, w.buf.Bytes(), CSPNonce(r.Context()), Traceparent(r.Context()))
+	h.Set("ETag", etag)
+	if policy.MaxAge > 0 {
+		h.Set("Cache-Control", "max-age="+strconv.Itoa(int(policy.MaxAge.Seconds())))
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	if !policy.LastModified.IsZero() {
+		h.Set("Last-Modified", policy.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	result := CacheMiss
+	if notModified(r, etag, policy.LastModified) {
+		result = CacheHit
+	}
+	recordCacheResult(r, result)
+
+	if result == CacheHit {
+		// The client keeps its cached body, so representation headers must not
+		// change; in particular a new CSP nonce would not match the cached scripts
+		for _, key := range []string{"Content-Type", "Content-Length", "Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+			h.Del(key)
+		}
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	h.Set("Content-Length", strconv.Itoa(w.buf.Len()))
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.buf.Bytes())
+	for _, fn := range w.decl.onFull {
+		fn()
+	}
+}
+
+// computeETag hashes the version and body; per-request values such as the CSP
+// nonce and traceparent are removed first since they change on every request
+func computeETag(version string, body []byte, perRequest ...string) string {
+	for _, value := range perRequest {
+		if value != "" {
+			body = bytes.ReplaceAll(body, []byte(value), nil)
+		}
+	}
+	sum := sha256.New()
+	sum.Write([]byte(version))
+	sum.Write([]byte{0})
+	sum.Write(body)
+	return `"` + hex.EncodeToString(sum.Sum(nil))[:32] + `"`
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since when absent
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses weak comparison, so compressed variants still match
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func recordCacheResult(r *http.Request, result string) {
+	ctx := r.Context()
+	trace.SpanFromContext(ctx).SetAttributes(cacheResultKey.String(result))
+	if httpCacheRequests == nil {
+		return
+	}
+
+	route := ""
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			route = tmpl
+		}
+	}
+	httpCacheRequests.Add(ctx, 1, metric.WithAttributes(
+		semconv.HTTPRouteKey.String(route),
+		cacheResultKey.String(result),
+	))
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestConditionalGetMiddleware(t *testing.T) {
+	modified := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	page := ConditionalGetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetCachePolicy(r, CachePolicy{Version: "abc123", LastModified: modified})
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<h1>Hello</h1>")
+	}))
+
+	rr := httptest.NewRecorder()
+	page.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || rr.Body.String() != "<h1>Hello</h1>" {
+		t.Fatalf("Expected full response, got %d %q", rr.Code, rr.Body.String())
+	}
+	if etag == "" || etag[0] != '"' {
+		t.Fatalf("Expected strong ETag, got %q", etag)
+	}
+	if rr.Header().Get("Cache-Control") != "no-cache" || rr.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+		t.Errorf("Unexpected validators: %v", rr.Header())
+	}
+
+	tests := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{"matching ETag", "If-None-Match", etag, http.StatusNotModified},
+		{"weakened ETag", "If-None-Match", "W/" + etag, http.StatusNotModified},
+		{"ETag list", "If-None-Match", `"other", ` + etag, http.StatusNotModified},
+		{"stale ETag", "If-None-Match", `"other"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", modified.Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", "If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(test.header, test.value)
+		rr := httptest.NewRecorder()
+		page.ServeHTTP(rr, req)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expectedStatus, rr.Code)
+		}
+		if rr.Code == http.StatusNotModified && (rr.Body.Len() != 0 || rr.Header().Get("Content-Type") != "") {
+			t.Errorf("%s: 304 should carry no body or representation headers", test.name)
+		}
+	}
+}
+
+func TestOnFullResponse(t *testing.T) {
+	full := 0
+	page := ConditionalGetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetCachePolicy(r, CachePolicy{Version: "abc123"})
+		io.WriteString(w, "<h1>Hello</h1>")
+		OnFullResponse(r, func() { full++ })
+	}))
+
+	rr := httptest.NewRecorder()
+	page.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if full != 1 {
+		t.Fatalf("Expected the callback after a full response, ran %d times", full)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	page.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotModified || full != 1 {
+		t.Errorf("Expected no callback for a 304, got %d after %d runs", rr.Code, full)
+	}
+
+	// Without the middleware the response is already full
+	OnFullResponse(httptest.NewRequest("GET", "/", nil), func() { full++ })
+	if full != 2 {
+		t.Errorf("Expected the callback to run at once outside the middleware, ran %d times", full)
+	}
+}
+
+func TestConditionalGetIgnoresNonce(t *testing.T) {
+	page := SecurityHeadersMiddleware(SecurityHeadersOptions{})(ConditionalGetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetCachePolicy(r, CachePolicy{Version: "abc123"})
+		io.WriteString(w, `<script nonce="`+CSPNonce(r.Context())+`"></script>`)
+	})))
+
+	first := httptest.NewRecorder()
+	page.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	second := httptest.NewRecorder()
+	page.ServeHTTP(second, req)
+
+	if second.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 despite a new nonce, got %d", second.Code)
+	}
+	if second.Header().Get("Content-Security-Policy") != "" {
+		t.Error("304 must not replace the cached policy, whose nonce matches the cached body")
+	}
+}
+
+func TestConditionalGetIgnoresTraceparent(t *testing.T) {
+	var traceID byte
+	page := ConditionalGetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetCachePolicy(r, CachePolicy{Version: "abc123"})
+		io.WriteString(w, `<meta name="traceparent" content="`+Traceparent(r.Context())+`">`)
+	}))
+	// Every request belongs to a new trace
+	traced := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID++
+		sc := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{traceID},
+			SpanID:  trace.SpanID{1},
+		})
+		page.ServeHTTP(w, r.WithContext(trace.ContextWithSpanContext(r.Context(), sc)))
+	})
+
+	first := httptest.NewRecorder()
+	traced.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	second := httptest.NewRecorder()
+	traced.ServeHTTP(second, req)
+
+	if second.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 despite a new traceparent, got %d", second.Code)
+	}
+}
+
+func TestConditionalGetDeclarations(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		policy        *CachePolicy
+		status        int
+		expectedCache string
+		expectETag    bool
+	}{
+		{"undeclared", "GET", nil, http.StatusOK, "", false},
+		{"no-store", "GET", &CachePolicy{NoStore: true}, http.StatusOK, "no-store", false},
+		{"max-age", "GET", &CachePolicy{MaxAge: time.Minute}, http.StatusOK, "max-age=60", true},
+		{"error status", "GET", &CachePolicy{}, http.StatusNotFound, "", false},
+		{"unsafe method", "POST", &CachePolicy{}, http.StatusOK, "", false},
+	}
+
+	for _, test := range tests {
+		handler := ConditionalGetMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if test.policy != nil {
+				SetCachePolicy(r, *test.policy)
+			}
+			w.WriteHeader(test.status)
+			io.WriteString(w, "body")
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(test.method, "/", nil))
+
+		if rr.Code != test.status || rr.Body.String() != "body" {
+			t.Errorf("%s: expected %d with body, got %d %q", test.name, test.status, rr.Code, rr.Body.String())
+		}
+		if got := rr.Header().Get("Cache-Control"); got != test.expectedCache {
+			t.Errorf("%s: expected Cache-Control %q, got %q", test.name, test.expectedCache, got)
+		}
+		if got := rr.Header().Get("ETag") != ""; got != test.expectETag {
+			t.Errorf("%s: expected ETag present to be %v", test.name, test.expectETag)
+		}
+	}
+}
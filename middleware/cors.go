@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults applied by CORSMiddleware when the corresponding option is empty
+var (
+	DefaultCORSMethods        = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	DefaultCORSAllowedHeaders = []string{"Accept", "Authorization", "Content-Type", "X-Api-Key", "X-Request-Id", "HX-Request", "HX-Current-URL", "HX-Target", "HX-Trigger", "X-Farcaster-Platform"}
+	DefaultCORSExposedHeaders = []string{"X-Request-Id", "HX-Trigger", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+)
+
+// CORSOptions configures CORSMiddleware
+type CORSOptions struct {
+	// AllowedOrigins lists origins such as "https://app.example.com", wildcard
+	// subdomains such as "https://*.farcaster.xyz", or "*" for any origin.
+	// Origins match the pattern's port only, the scheme's default when it has
+	// none; "https://*.example.com:*" allows any port. An empty list disables CORS.
+	AllowedOrigins []string
+	// AllowedMethods defaults to DefaultCORSMethods
+	AllowedMethods []string
+	// AllowedHeaders lists request headers accepted in preflights; defaults to DefaultCORSAllowedHeaders
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers readable by scripts; defaults to DefaultCORSExposedHeaders
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization headers;
+	// the matching origin is then echoed instead of "*"
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result; zero omits the header
+	MaxAge time.Duration
+}
+
+// originPattern is an allowed origin split for matching
+type originPattern struct {
+	scheme string
+	// host is an exact host name, or the suffix after "*." for wildcards
+	host     string
+	wildcard bool
+	// port is empty for the scheme's default port, or "*" for any port
+	port string
+}
+
+// CORSMiddleware answers preflight requests and adds CORS headers for
+// allowed origins. The subrouter it is applied to must route OPTIONS
+// requests (see routes) so that preflights reach the middleware.
+func CORSMiddleware(opts CORSOptions) func(http.Handler) http.Handler {
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = DefaultCORSMethods
+	}
+	if len(opts.AllowedHeaders) == 0 {
+		opts.AllowedHeaders = DefaultCORSAllowedHeaders
+	}
+	if len(opts.ExposedHeaders) == 0 {
+		opts.ExposedHeaders = DefaultCORSExposedHeaders
+	}
+
+	anyOrigin := slices.Contains(opts.AllowedOrigins, "*")
+	var patterns []originPattern
+	for _, origin := range opts.AllowedOrigins {
+		if p, ok := parseOriginPattern(origin); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	allowedMethods := strings.Join(opts.AllowedMethods, ", ")
+	exposedHeaders := strings.Join(opts.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		if len(opts.AllowedOrigins) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if !anyOrigin || opts.AllowCredentials {
+				addVary(h, "Origin")
+			}
+			if preflight {
+				addVary(h, "Access-Control-Request-Method")
+				addVary(h, "Access-Control-Request-Headers")
+			}
+
+			if origin == "" || !(anyOrigin || originAllowed(origin, patterns)) {
+				if preflight {
+					// Without CORS headers the browser rejects the actual request
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin && !opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				h.Set("Access-Control-Expose-Headers", exposedHeaders)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !slices.Contains(opts.AllowedMethods, strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))) {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			headers, ok := allowedRequestHeaders(r.Header.Get("Access-Control-Request-Headers"), opts.AllowedHeaders)
+			if !ok {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if headers != "" {
+				h.Set("Access-Control-Allow-Headers", headers)
+			}
+			h.Set("Access-Control-Allow-Methods", allowedMethods)
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// parseOriginPattern splits "scheme://host[:port]", where host may start
+// with "*." and port may be "*"
+func parseOriginPattern(origin string) (originPattern, bool) {
+	scheme, host, ok := strings.Cut(strings.ToLower(strings.TrimSuffix(origin, "/")), "://")
+	if !ok || host == "" {
+		return originPattern{}, false
+	}
+	p := originPattern{scheme: scheme, host: host}
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+		p.host, p.port = host[:i], host[i+1:]
+	}
+	p.host = strings.Trim(p.host, "[]")
+	if suffix, ok := strings.CutPrefix(p.host, "*."); ok {
+		p.host, p.wildcard = suffix, true
+	}
+	return p, p.host != ""
+}
+
+// originAllowed matches origin against the patterns; wildcards match
+// subdomains at any depth but not the bare domain
+func originAllowed(origin string, patterns []originPattern) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host, port := u.Hostname(), u.Port()
+	for _, p := range patterns {
+		if p.scheme != u.Scheme || (p.port != "*" && p.port != port) {
+			continue
+		}
+		if p.wildcard {
+			if strings.HasSuffix(host, "."+p.host) {
+				return true
+			}
+		} else if p.host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedRequestHeaders checks the comma-separated preflight headers against
+// the allowlist, returning them for Access-Control-Allow-Headers
+func allowedRequestHeaders(requested string, allowed []string) (string, bool) {
+	var headers []string
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		if !slices.ContainsFunc(allowed, func(a string) bool { return strings.EqualFold(a, header) }) {
+			return "", false
+		}
+		headers = append(headers, header)
+	}
+	return strings.Join(headers, ", "), true
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	var patterns []originPattern
+	for _, origin := range []string{"https://app.example.com", "https://*.farcaster.xyz", "http://localhost:5173", "https://*.example.org:8443", "http://*.dev.example.net:*", "http://[::1]:3000"} {
+		p, ok := parseOriginPattern(origin)
+		if !ok {
+			t.Fatalf("Failed to parse %q", origin)
+		}
+		patterns = append(patterns, p)
+	}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://miniapp.farcaster.xyz", true},
+		{"https://a.b.farcaster.xyz", true},
+		{"https://farcaster.xyz", false},
+		{"https://evilfarcaster.xyz", false},
+		{"http://localhost:5173", true},
+		{"http://localhost:8080", false},
+		{"https://app.example.com:8443", false},
+		{"https://miniapp.farcaster.xyz:8443", false},
+		{"https://a.example.org:8443", true},
+		{"https://a.example.org", false},
+		{"https://a.example.org:9443", false},
+		{"http://a.dev.example.net:3000", true},
+		{"http://a.dev.example.net", true},
+		{"http://[::1]:3000", true},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		if got := originAllowed(test.origin, patterns); got != test.allowed {
+			t.Errorf("%s: expected allowed=%v, got %v", test.origin, test.allowed, got)
+		}
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	handler := CORSMiddleware(CORSOptions{
+		AllowedOrigins:   []string{"https://*.farcaster.xyz"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Actual request from an allowed origin
+	req := httptest.NewRequest("GET", "/api/v1/time", nil)
+	req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://miniapp.farcaster.xyz" {
+		t.Errorf("Expected echoed origin, got %q", rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rr.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("Expected credentials to be allowed")
+	}
+	if rr.Header().Get("Access-Control-Expose-Headers") == "" || rr.Header().Get("Vary") != "Origin" {
+		t.Errorf("Expected exposed headers and Vary: Origin, got %v", rr.Header())
+	}
+
+	// Preflight
+	req = httptest.NewRequest("OPTIONS", "/api/v1/clicks", nil)
+	req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, hx-request")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 preflight, got %d", rr.Code)
+	}
+	if rr.Header().Get("Access-Control-Allow-Headers") != "content-type, hx-request" {
+		t.Errorf("Expected requested headers to be allowed, got %q", rr.Header().Get("Access-Control-Allow-Headers"))
+	}
+	if rr.Header().Get("Access-Control-Max-Age") != "600" || rr.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Expected cached preflight with allowed methods, got %v", rr.Header())
+	}
+
+	// Preflights that ask for too much, or come from unknown origins, get no CORS headers
+	for name, setup := range map[string]func(*http.Request){
+		"disallowed method": func(r *http.Request) { r.Header.Set("Access-Control-Request-Method", "DELETE") },
+		"disallowed header": func(r *http.Request) { r.Header.Set("Access-Control-Request-Headers", "x-secret") },
+		"unknown origin":    func(r *http.Request) { r.Header.Set("Origin", "https://evil.example.com") },
+	} {
+		req := httptest.NewRequest("OPTIONS", "/api/v1/clicks", nil)
+		req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		setup(req)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Errorf("%s: preflight should be rejected", name)
+		}
+	}
+}
+
+func TestCORSMiddlewareAnyOrigin(t *testing.T) {
+	handler := CORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Access-Control-Allow-Origin") != "*" || rr.Header().Get("Vary") != "" {
+		t.Errorf("Expected wildcard origin without Vary, got %v", rr.Header())
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// inFlightPollInterval is how often WaitForInFlight checks the request count
+const inFlightPollInterval = 25 * time.Millisecond
+
+var (
+	// inFlight mirrors http.server.active_requests for the shutdown coordinator
+	inFlight atomic.Int64
+
+	// drainCtx is cancelled by StartDrain
+	drainCtx, startDrain = context.WithCancel(context.Background())
+)
+
+// InFlightRequests returns the number of requests inside ObservabilityMiddleware
+func InFlightRequests() int64 {
+	return inFlight.Load()
+}
+
+// WaitForInFlight blocks until no requests are in flight or ctx is done
+func WaitForInFlight(ctx context.Context) error {
+	ticker := time.NewTicker(inFlightPollInterval)
+	defer ticker.Stop()
+	for inFlight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// StartDrain tells long-lived responses such as streams to finish; it is safe to call more than once
+func StartDrain() {
+	startDrain()
+}
+
+// Draining is closed by StartDrain. TimeoutMiddleware cancels the context of
+// streaming responses when it closes, so handlers that end their stream on a
+// cancelled context finish before the server stops waiting for them.
+func Draining() <-chan struct{} {
+	return drainCtx.Done()
+}
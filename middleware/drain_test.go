@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWaitForInFlight(t *testing.T) {
+	release := make(chan struct{})
+	handler := ObservabilityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+		close(done)
+	}()
+	for InFlightRequests() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := WaitForInFlight(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to time out waiting for the request, got %v", err)
+	}
+
+	close(release)
+	<-done
+	if err := WaitForInFlight(context.Background()); err != nil || InFlightRequests() != 0 {
+		t.Errorf("Expected no requests in flight, got %d: %v", InFlightRequests(), err)
+	}
+}
+
+// resetDrain undoes StartDrain for the tests that follow
+func resetDrain() {
+	drainCtx, startDrain = context.WithCancel(context.Background())
+}
+
+func TestStartDrain(t *testing.T) {
+	t.Cleanup(resetDrain)
+	select {
+	case <-Draining():
+		t.Fatal("Draining should stay open until StartDrain")
+	default:
+	}
+	StartDrain()
+	StartDrain()
+	select {
+	case <-Draining():
+	default:
+		t.Error("Draining should be closed after StartDrain")
+	}
+}
+
+func TestStartDrainEndsStreams(t *testing.T) {
+	t.Cleanup(resetDrain)
+	streaming := make(chan struct{}, 2)
+	handler := ObservabilityMiddleware(TimeoutMiddleware(time.Minute, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		http.NewResponseController(w).Flush()
+		streaming <- struct{}{}
+		<-r.Context().Done()
+		w.Write([]byte("data: bye\n\n"))
+	})))
+
+	rr := httptest.NewRecorder()
+	go handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events", nil))
+	<-streaming
+	StartDrain()
+
+	// Well within the drain budget, though the stream's timeout is a minute away
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := WaitForInFlight(ctx); err != nil {
+		t.Fatalf("Expected the stream to end on drain, got %v", err)
+	}
+	if rr.Body.String() != "data: first\n\ndata: bye\n\n" {
+		t.Errorf("Expected the stream to close cleanly, got %q", rr.Body.String())
+	}
+
+	// Streams starting during drain end at once
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/events", nil))
+	if rr.Body.String() != "data: first\n\ndata: bye\n\n" {
+		t.Errorf("Expected a stream started during drain to end, got %q", rr.Body.String())
+	}
+}
+
+func TestStartDrainLeavesBufferedResponses(t *testing.T) {
+	t.Cleanup(resetDrain)
+	StartDrain()
+	handler := TimeoutMiddleware(time.Minute, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Err() != nil {
+			t.Error("Buffered responses should not be cancelled by drain")
+		}
+		w.Write([]byte("ok"))
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusOK || rr.Body.String() != "ok" {
+		t.Errorf("Expected the response to complete, got %d %q", rr.Code, rr.Body.String())
+	}
+}
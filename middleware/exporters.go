@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExporterStatus describes a telemetry exporter for diagnostics
+type ExporterStatus struct {
+	// Signal is "traces", "metrics" or "logs"
+	Signal   string
+	Enabled  bool
+	Endpoint string
+	// Error explains why a disabled exporter could not be started
+	Error string
+}
+
+// TelemetryError is the most recent error reported by the OpenTelemetry SDK,
+// such as a failed export
+type TelemetryError struct {
+	Message string
+	Time    time.Time
+	// Count is the number of errors reported since startup
+	Count int64
+}
+
+var (
+	exportersMu      sync.Mutex
+	exporterStatuses = map[string]ExporterStatus{}
+	lastTelemetryErr TelemetryError
+)
+
+// SetExporterStatus records the outcome of starting the exporter for signal;
+// a nil err marks it enabled
+func SetExporterStatus(signal, endpoint string, err error) {
+	status := ExporterStatus{Signal: signal, Enabled: err == nil, Endpoint: endpoint}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporterStatuses[signal] = status
+}
+
+// ExporterStatuses returns the recorded exporters ordered by signal
+func ExporterStatuses() []ExporterStatus {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	statuses := make([]ExporterStatus, 0, len(exporterStatuses))
+	for _, status := range exporterStatuses {
+		statuses = append(statuses, status)
+	}
+	slices.SortFunc(statuses, func(a, b ExporterStatus) int { return strings.Compare(a.Signal, b.Signal) })
+	return statuses
+}
+
+// RecordTelemetryError is installed as the OpenTelemetry error handler; it
+// keeps the last error for diagnostics and logs it on the telemetry logger
+func RecordTelemetryError(err error) {
+	exportersMu.Lock()
+	lastTelemetryErr = TelemetryError{Message: err.Error(), Time: time.Now(), Count: lastTelemetryErr.Count + 1}
+	exportersMu.Unlock()
+	Logger(TelemetryLogger).Warn("OpenTelemetry error", "error", err)
+}
+
+// LastTelemetryError returns the most recent SDK error; Count is zero if none occurred
+func LastTelemetryError() TelemetryError {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	return lastTelemetryErr
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestExporterStatuses(t *testing.T) {
+	SetExporterStatus("traces", "collector:4318", nil)
+	SetExporterStatus("logs", "", errors.New("OTEL_EXPORTER_OTLP_ENDPOINT is not set"))
+
+	statuses := ExporterStatuses()
+	if len(statuses) < 2 || statuses[0].Signal != "logs" {
+		t.Fatalf("Expected statuses ordered by signal, got %+v", statuses)
+	}
+	if statuses[0].Enabled || statuses[0].Error == "" {
+		t.Errorf("Expected logs to be disabled with a reason, got %+v", statuses[0])
+	}
+
+	before := LastTelemetryError().Count
+	RecordTelemetryError(errors.New("export failed"))
+	if last := LastTelemetryError(); last.Count != before+1 || last.Message != "export failed" {
+		t.Errorf("Unexpected last telemetry error: %+v", last)
+	}
+}
+
+func TestRecordTelemetryErrorLogger(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	// The otel sink's level must not silence SDK errors
+	old := SetLogLevel(OTelSink, slog.LevelError+4)
+	t.Cleanup(func() { SetLogLevel(OTelSink, old) })
+	RecordTelemetryError(errors.New("export failed"))
+	if !strings.Contains(buf.String(), `"logger":"telemetry"`) {
+		t.Errorf("Expected the error on the telemetry logger, got %s", buf.String())
+	}
+}
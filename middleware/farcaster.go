@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/trace"
+
+	"hello-world/models"
+)
+
+// SessionCookie names the cookie identifying a browser session
+const SessionCookie = "session"
+
+// Keys under which the Farcaster client context is logged, traced and propagated as baggage
+const (
+	farcasterFIDKey          = "farcaster.fid"
+	farcasterClientFIDKey    = "farcaster.client_fid"
+	farcasterPlatformTypeKey = "farcaster.platform_type"
+	farcasterLocationTypeKey = "farcaster.location_type"
+)
+
+// FarcasterContextLookup returns the Mini App context captured for a session
+type FarcasterContextLookup func(sessionID string) (*models.MiniAppContext, bool)
+
+// FarcasterContextMiddleware attaches the Mini App context captured for the
+// request's session. Its FID, client FID, platform and launch location are
+// added to the span, to every log record and to the baggage propagated to
+// downstream calls. The context is client-reported and is not authentication,
+// so it never sets the FID used by ContextWithFID; it only keys rate limits.
+func FarcasterContextMiddleware(lookup FarcasterContextLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(SessionCookie)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			fc, ok := lookup(cookie.Value)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithFarcasterContext(r.Context(), fc)))
+		})
+	}
+}
+
+// WithFarcasterContext returns ctx carrying fc, with its identifying fields
+// added to the current span, log records and baggage
+func WithFarcasterContext(ctx context.Context, fc *models.MiniAppContext) context.Context {
+	values := farcasterContextValues(fc)
+
+	attrs := make([]attribute.KeyValue, 0, len(values))
+	logAttrs := make([]slog.Attr, 0, len(values))
+	bag := baggage.FromContext(ctx)
+	for _, kv := range values {
+		attrs = append(attrs, attribute.String(kv[0], kv[1]))
+		logAttrs = append(logAttrs, slog.String(kv[0], kv[1]))
+		if member, err := baggage.NewMember(kv[0], kv[1]); err == nil {
+			if updated, err := bag.SetMember(member); err == nil {
+				bag = updated
+			}
+		}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+
+	ctx = context.WithValue(ctx, farcasterContextKey, fc)
+	ctx = ContextWithLogAttrs(ctx, logAttrs...)
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+// FarcasterContextFromContext returns the Mini App context of the request's session, if captured
+func FarcasterContextFromContext(ctx context.Context) (*models.MiniAppContext, bool) {
+	fc, ok := ctx.Value(farcasterContextKey).(*models.MiniAppContext)
+	return fc, ok
+}
+
+// farcasterContextValues lists the fields of fc recorded in telemetry; names
+// and profile pictures are left out
+func farcasterContextValues(fc *models.MiniAppContext) [][2]string {
+	values := [][2]string{
+		{farcasterFIDKey, strconv.FormatUint(fc.User.FID, 10)},
+		{farcasterClientFIDKey, strconv.FormatUint(fc.Client.ClientFID, 10)},
+	}
+	if fc.Client.PlatformType != "" {
+		values = append(values, [2]string{farcasterPlatformTypeKey, fc.Client.PlatformType})
+	}
+	if fc.Location != nil {
+		values = append(values, [2]string{farcasterLocationTypeKey, fc.Location.Type})
+	}
+	return values
+}
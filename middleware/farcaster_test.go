@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"hello-world/models"
+)
+
+func TestFarcasterContextMiddleware(t *testing.T) {
+	captured := &models.MiniAppContext{
+		User:     models.MiniAppUser{FID: 3, Username: "dwr"},
+		Client:   models.MiniAppClient{PlatformType: "mobile", ClientFID: 9152},
+		Location: &models.MiniAppLocation{Type: models.LocationLauncher},
+	}
+	lookup := func(sessionID string) (*models.MiniAppContext, bool) {
+		return captured, sessionID == "known"
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewTraceHandler(slog.NewJSONHandler(&buf, nil)))
+	var fc *models.MiniAppContext
+	var bag baggage.Baggage
+	handler := FarcasterContextMiddleware(lookup)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fc, _ = FarcasterContextFromContext(r.Context())
+		bag = baggage.FromContext(r.Context())
+		logger.InfoContext(r.Context(), "hello")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookie, Value: "known"})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if fc != captured {
+		t.Fatal("Expected the captured context in the request context")
+	}
+	if bag.Member("farcaster.fid").Value() != "3" || bag.Member("farcaster.platform_type").Value() != "mobile" {
+		t.Errorf("Expected Farcaster baggage, got %s", bag.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["farcaster.fid"] != "3" || record["farcaster.location_type"] != "launcher" {
+		t.Errorf("Expected Farcaster log attributes, got %v", record)
+	}
+	if _, ok := record["username"]; ok {
+		t.Error("Usernames must not be logged")
+	}
+
+	buf.Reset()
+	req = httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookie, Value: "unknown"})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if fc != nil {
+		t.Error("Unknown sessions should have no context")
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import "net/http"
+
+// ToastRegion is the id of the element in the base layout that collects toast fragments
+const ToastRegion = "toast-region"
+
+// IsHTMX reports whether r was issued by htmx
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// RetargetToast makes htmx append the response to the toast region instead of the request's target
+func RetargetToast(w http.ResponseWriter) {
+	w.Header().Set("HX-Retarget", "#"+ToastRegion)
+	w.Header().Set("HX-Reswap", "beforeend")
+}
@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	httpTimeouts      metric.Int64Counter
+	httpBodyTooLarge  metric.Int64Counter
+	limitsMetricsOnce sync.Once
+)
+
+func initLimitsMetrics() {
+	var err error
+	httpTimeouts, err = observabilityMeter.Int64Counter(
+		"http.server.request.timeouts",
+		metric.WithDescription("Requests cancelled for exceeding their route timeout"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize timeout metric", "error", err)
+	}
+	httpBodyTooLarge, err = observabilityMeter.Int64Counter(
+		"http.server.request.body_too_large",
+		metric.WithDescription("Requests rejected for exceeding their route body size limit"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize body size metric", "error", err)
+	}
+}
+
+// TimeoutMiddleware cancels the request context after timeout. If the handler
+// has not finished by then, its output is discarded and onTimeout renders the
+// response, typically a 503 page or HTMX toast. Like http.TimeoutHandler the
+// handler runs in its own goroutine with a buffered response. Responses that
+// flush or are event streams are written through instead, so a timeout only
+// cancels their context, as does StartDrain so streams end before shutdown.
+// Panics are re-raised for RecoveryMiddleware with the handler's stack. A
+// zero timeout disables the middleware.
+func TimeoutMiddleware(timeout time.Duration, onTimeout http.Handler) func(http.Handler) http.Handler {
+	limitsMetricsOnce.Do(initLimitsMetrics)
+
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutResponseWriter{w: w, header: make(http.Header), status: http.StatusOK, cancel: cancel}
+			stopDrain := context.AfterFunc(drainCtx, tw.endStream)
+			defer stopDrain()
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if rec := recover(); rec != nil {
+						if rec != http.ErrAbortHandler {
+							// The stack of this goroutine, not the re-panic's, shows the handler
+							rec = &handlerPanic{value: rec, stack: debug.Stack()}
+						}
+						panicked <- rec
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case rec := <-panicked:
+				panic(rec)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				if !tw.streaming {
+					tw.writeBuffered()
+				}
+			case <-ctx.Done():
+				tw.mu.Lock()
+				streaming := tw.streaming
+				tw.timedOut = !streaming
+				tw.mu.Unlock()
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					recordLimitExceeded(r, httpTimeouts, "request.timeout", attribute.String("request.timeout", timeout.String()))
+					slog.WarnContext(r.Context(), "Request timed out", "timeout", timeout.String(), "path", r.URL.Path, "streaming", streaming)
+				}
+				if streaming {
+					// The response has started; the handler ends the stream
+					// once it sees the cancelled context
+					select {
+					case rec := <-panicked:
+						panic(rec)
+					case <-done:
+					}
+					return
+				}
+				// The handler runs on and may still panic with nobody to recover it
+				go logLatePanic(r, panicked, done)
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					// The client went away; nobody is waiting for a response
+					return
+				}
+				onTimeout.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// logLatePanic logs and counts a panic of a handler that outlived its
+// timeout, with the handler's stack, or returns once the handler finishes
+func logLatePanic(r *http.Request, panicked <-chan any, done <-chan struct{}) {
+	select {
+	case rec := <-panicked:
+		hp, ok := rec.(*handlerPanic)
+		if !ok {
+			// http.ErrAbortHandler is a deliberate abort
+			return
+		}
+		ctx := r.Context()
+		slog.ErrorContext(ctx, "Panic after request timeout",
+			"panic", fmt.Sprint(hp.value),
+			"method", r.Method,
+			"path", r.URL.Path,
+			"stack", string(hp.stack),
+		)
+		if httpPanics != nil {
+			httpPanics.Add(ctx, 1, metric.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)))
+		}
+	case <-done:
+	}
+}
+
+// timeoutResponseWriter buffers the handler's response until it completes in
+// time, or writes it through to w once the handler starts streaming
+type timeoutResponseWriter struct {
+	w           http.ResponseWriter
+	mu          sync.Mutex
+	header      http.Header
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+	streaming   bool
+	// cancel ends the handler's context when a stream must stop for drain
+	cancel context.CancelFunc
+}
+
+func (w *timeoutResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutResponseWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader || code < http.StatusOK {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	if isEventStream(w.header) {
+		w.startStreaming()
+	}
+}
+
+func (w *timeoutResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	if !w.streaming && isEventStream(w.header) {
+		w.startStreaming()
+	}
+	if w.streaming {
+		return w.w.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush writes the response so far and streams the rest
+func (w *timeoutResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.wroteHeader = true
+	w.startStreaming()
+	http.NewResponseController(w.w).Flush()
+}
+
+// startStreaming writes the buffered response and switches to writing
+// through; callers hold w.mu
+func (w *timeoutResponseWriter) startStreaming() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	w.writeBuffered()
+	w.buf.Reset()
+	if drainCtx.Err() != nil {
+		w.cancel()
+	}
+}
+
+// endStream cancels the handler's context if it is streaming; buffered
+// responses are left to finish as usual during drain
+func (w *timeoutResponseWriter) endStream() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.streaming {
+		w.cancel()
+	}
+}
+
+// writeBuffered copies the buffered header and body to w; callers hold w.mu
+func (w *timeoutResponseWriter) writeBuffered() {
+	dst := w.w.Header()
+	for key, values := range w.header {
+		dst[key] = values
+	}
+	w.w.WriteHeader(w.status)
+	w.w.Write(w.buf.Bytes())
+}
+
+// isEventStream reports whether header declares a server-sent event stream
+func isEventStream(header http.Header) bool {
+	return strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// MaxBodyMiddleware limits request bodies to limit bytes with http.MaxBytesReader.
+// Requests declaring a larger Content-Length are rejected up front through
+// onTooLarge; chunked bodies fail with *http.MaxBytesError when read past the
+// limit. A zero limit disables the middleware.
+func MaxBodyMiddleware(limit int64, onTooLarge http.Handler) func(http.Handler) http.Handler {
+	limitsMetricsOnce.Do(initLimitsMetrics)
+
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limitAttr := attribute.Int64("request.body.limit", limit)
+			if r.ContentLength > limit {
+				recordLimitExceeded(r, httpBodyTooLarge, "request.body_too_large", limitAttr,
+					semconv.HTTPRequestBodySizeKey.Int64(r.ContentLength))
+				slog.WarnContext(r.Context(), "Request body too large", "limit", limit, "content_length", r.ContentLength)
+				onTooLarge.ServeHTTP(w, r)
+				return
+			}
+
+			r.Body = &maxBodyReader{
+				ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+				onExceeded: func() {
+					recordLimitExceeded(r, httpBodyTooLarge, "request.body_too_large", limitAttr)
+					slog.WarnContext(r.Context(), "Request body too large", "limit", limit)
+				},
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxBodyReader records the first read that runs past the body limit
+type maxBodyReader struct {
+	io.ReadCloser
+	onExceeded func()
+	reported   bool
+}
+
+func (b *maxBodyReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if !b.reported && errors.As(err, &maxErr) {
+		b.reported = true
+		b.onExceeded()
+	}
+	return n, err
+}
+
+// recordLimitExceeded adds a span event and increments counter for a rejected request
+func recordLimitExceeded(r *http.Request, counter metric.Int64Counter, event string, attrs ...attribute.KeyValue) {
+	ctx := r.Context()
+	trace.SpanFromContext(ctx).AddEvent(event, trace.WithAttributes(attrs...))
+	if counter == nil {
+		return
+	}
+
+	route := ""
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			route = tmpl
+		}
+	}
+	counter.Add(ctx, 1, metric.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(r.Method),
+		semconv.HTTPRouteKey.String(route),
+	))
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var unavailable = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "timed out", http.StatusServiceUnavailable)
+})
+
+func TestTimeoutMiddleware(t *testing.T) {
+	cancelled := make(chan error, 1)
+	slow := TimeoutMiddleware(10*time.Millisecond, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		cancelled <- r.Context().Err()
+		w.Write([]byte("too late"))
+	}))
+
+	rr := httptest.NewRecorder()
+	slow.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Code != http.StatusServiceUnavailable || strings.Contains(rr.Body.String(), "too late") {
+		t.Errorf("Expected only the 503 response, got %d %q", rr.Code, rr.Body.String())
+	}
+	if err := <-cancelled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Handler context should hit its deadline, got %v", err)
+	}
+}
+
+func TestTimeoutMiddlewarePassesThrough(t *testing.T) {
+	fast := TimeoutMiddleware(time.Second, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("Handler context should carry a deadline")
+		}
+		w.Header().Set("X-Test", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	}))
+
+	rr := httptest.NewRecorder()
+	fast.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Code != http.StatusCreated || rr.Body.String() != "done" || rr.Header().Get("X-Test") != "yes" {
+		t.Errorf("Expected the handler's response, got %d %q %v", rr.Code, rr.Body.String(), rr.Header())
+	}
+}
+
+func TestTimeoutMiddlewareRepanics(t *testing.T) {
+	handler := TimeoutMiddleware(time.Second, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	defer func() {
+		hp, ok := recover().(*handlerPanic)
+		if !ok || hp.value != "boom" {
+			t.Fatalf("Expected panic to reach the caller, got %v", hp)
+		}
+		if !strings.Contains(string(hp.stack), "TestTimeoutMiddlewareRepanics") {
+			t.Errorf("Expected the handler's stack, got %s", hp.stack)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestTimeoutMiddlewareStreams(t *testing.T) {
+	cancelled := make(chan struct{})
+	handler := TimeoutMiddleware(20*time.Millisecond, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		http.NewResponseController(w).Flush()
+		<-r.Context().Done()
+		w.Write([]byte("data: bye\n\n"))
+		close(cancelled)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	select {
+	case <-cancelled:
+	default:
+		t.Fatal("Expected ServeHTTP to wait for the streaming handler")
+	}
+	if rr.Code != http.StatusOK || rr.Body.String() != "data: first\n\ndata: bye\n\n" || !rr.Flushed {
+		t.Errorf("Expected the stream to be written through, got %d %q flushed=%v", rr.Code, rr.Body.String(), rr.Flushed)
+	}
+}
+
+func TestRecoveryMiddlewareLogsHandlerStack(t *testing.T) {
+	var logs strings.Builder
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	handler := RecoveryMiddleware(unavailable)(TimeoutMiddleware(time.Second, unavailable)(http.HandlerFunc(panickingHandler)))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected the error page, got %d", rr.Code)
+	}
+	if !strings.Contains(logs.String(), "panickingHandler") || !strings.Contains(logs.String(), "panic=boom") {
+		t.Errorf("Expected the handler's frames in the log, got %s", logs.String())
+	}
+}
+
+func TestTimeoutMiddlewareLogsLatePanic(t *testing.T) {
+	var logs syncBuffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	handler := TimeoutMiddleware(10*time.Millisecond, unavailable)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		panickingHandler(w, r)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected the timeout response, got %d", rr.Code)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(logs.String(), "Panic after request timeout") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if out := logs.String(); !strings.Contains(out, "panic=boom") || !strings.Contains(out, "panickingHandler") {
+		t.Errorf("Expected the late panic with the handler's stack in the log, got %s", out)
+	}
+}
+
+// syncBuffer is a strings.Builder safe for a logger on another goroutine
+type syncBuffer struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+func TestMaxBodyMiddleware(t *testing.T) {
+	tooLarge := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	})
+	handler := MaxBodyMiddleware(8, tooLarge)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			var maxErr *http.MaxBytesError
+			if !errors.As(err, &maxErr) {
+				t.Errorf("Expected MaxBytesError, got %v", err)
+			}
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{"within limit", "small", false, http.StatusOK},
+		{"declared too large", "far too large", false, http.StatusRequestEntityTooLarge},
+		{"chunked too large", "far too large", true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.chunked {
+			req.ContentLength = -1
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.expectedStatus, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	// RootLogger is the name of the level that guards the default logger
+	RootLogger = "root"
+	// AuditLogger records runtime changes to telemetry settings
+	AuditLogger = "audit"
+	// TelemetryLogger reports OpenTelemetry SDK errors; it is named apart from
+	// the otel sink so the two levels are set separately
+	TelemetryLogger = "telemetry"
+)
+
+var (
+	logLevelsMu sync.RWMutex
+	logLevels   = map[string]*slog.LevelVar{
+		RootLogger:      new(slog.LevelVar),
+		AuditLogger:     new(slog.LevelVar),
+		TelemetryLogger: new(slog.LevelVar),
+	}
+)
+
+// LevelHandler filters records below a level that can be changed at runtime
+type LevelHandler struct {
+	level slog.Leveler
+	next  slog.Handler
+}
+
+// NewLevelHandler wraps handler so that only records at or above level are handled
+func NewLevelHandler(level slog.Leveler, handler slog.Handler) *LevelHandler {
+	return &LevelHandler{level: level, next: handler}
+}
+
+func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *LevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LevelHandler{level: h.level, next: h.next.WithAttrs(attrs)}
+}
+
+func (h *LevelHandler) WithGroup(name string) slog.Handler {
+	return &LevelHandler{level: h.level, next: h.next.WithGroup(name)}
+}
+
+// Unwrap returns the handler wrapped by h
+func (h *LevelHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// LogLevel returns the adjustable level for the named logger, creating it
+// from the root level on first use
+func LogLevel(name string) *slog.LevelVar {
+	logLevelsMu.RLock()
+	lv, ok := logLevels[name]
+	logLevelsMu.RUnlock()
+	if ok {
+		return lv
+	}
+
+	logLevelsMu.Lock()
+	defer logLevelsMu.Unlock()
+	if lv, ok := logLevels[name]; ok {
+		return lv
+	}
+	lv = new(slog.LevelVar)
+	lv.Set(logLevels[RootLogger].Level())
+	logLevels[name] = lv
+	return lv
+}
+
+// SetLogLevel changes the level of the named logger and returns the previous level
+func SetLogLevel(name string, level slog.Level) slog.Level {
+	lv := LogLevel(name)
+	old := lv.Level()
+	lv.Set(level)
+	return old
+}
+
+// LogLevels returns a snapshot of all known logger levels
+func LogLevels() map[string]slog.Level {
+	logLevelsMu.RLock()
+	defer logLevelsMu.RUnlock()
+	levels := make(map[string]slog.Level, len(logLevels))
+	for name, lv := range logLevels {
+		levels[name] = lv.Level()
+	}
+	return levels
+}
+
+// ParseLevel parses a level name such as "debug" or "warn+2"
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+// Logger returns a logger named name whose minimum level is controlled
+// independently of the root level. It is built on the current default
+// handler, so call it at use time rather than caching the result.
+func Logger(name string) *slog.Logger {
+	handler := slog.Default().Handler()
+	if lh, ok := handler.(*LevelHandler); ok {
+		handler = lh.Unwrap()
+	}
+	return slog.New(NewLevelHandler(LogLevel(name), handler)).With("logger", name)
+}
+
+// ChangeLogLevel sets the level of the named logger and audits the change
+func ChangeLogLevel(ctx context.Context, name string, level slog.Level, source string) {
+	old := SetLogLevel(name, level)
+	Logger(AuditLogger).InfoContext(ctx, "Log level changed",
+		"target", name,
+		"old_level", old.String(),
+		"new_level", level.String(),
+		"source", source,
+	)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandlerFiltersBelowLevel(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	logger := slog.New(NewLevelHandler(level, slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") {
+		t.Error("Info record should be filtered at warn level")
+	}
+	if !strings.Contains(buf.String(), "shown") {
+		t.Error("Warn record should pass at warn level")
+	}
+
+	// Lowering the level takes effect immediately, including for derived loggers
+	level.Set(slog.LevelDebug)
+	logger.With("k", "v").WithGroup("g").Debug("now visible")
+	if !strings.Contains(buf.String(), "now visible") {
+		t.Error("Debug record should pass after lowering the level")
+	}
+}
+
+func TestLogLevelRegistry(t *testing.T) {
+	SetLogLevel(RootLogger, slog.LevelWarn)
+	defer SetLogLevel(RootLogger, slog.LevelInfo)
+
+	// New loggers start at the root level
+	if got := LogLevel("test-new").Level(); got != slog.LevelWarn {
+		t.Errorf("Expected new logger to inherit root level WARN, got %v", got)
+	}
+
+	old := SetLogLevel("test-new", slog.LevelDebug)
+	if old != slog.LevelWarn {
+		t.Errorf("Expected previous level WARN, got %v", old)
+	}
+	if got := LogLevels()["test-new"]; got != slog.LevelDebug {
+		t.Errorf("Expected snapshot level DEBUG, got %v", got)
+	}
+}
+
+func TestLoggerIgnoresRootLevel(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	SetLogLevel(RootLogger, slog.LevelError)
+	defer SetLogLevel(RootLogger, slog.LevelInfo)
+	slog.SetDefault(slog.New(NewLevelHandler(LogLevel(RootLogger),
+		slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	SetLogLevel("test-named", slog.LevelDebug)
+	Logger("test-named").DebugContext(context.Background(), "named debug")
+	slog.Info("root info")
+
+	if !strings.Contains(buf.String(), "named debug") || !strings.Contains(buf.String(), "logger=test-named") {
+		t.Errorf("Named logger should log at its own level, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "root info") {
+		t.Error("Root logger should still filter below its level")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"INFO", slog.LevelInfo, false},
+		{" warn ", slog.LevelWarn, false},
+		{"error+2", slog.LevelError + 2, false},
+		{"verbose", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLevel(test.input)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, wantErr %v", test.input, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
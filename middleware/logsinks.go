@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Names of the log sinks; their levels live in the same registry as logger levels
+const (
+	ConsoleSink = "console"
+	OTelSink    = "otel"
+)
+
+// FanoutHandler sends every record to each of its handlers that is enabled for it
+type FanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// NewFanoutHandler creates a handler that writes to all of handlers
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &FanoutHandler{handlers: handlers}
+}
+
+// ReplaceAttrFunc rewrites an attribute, following the slog.HandlerOptions.ReplaceAttr
+// contract: groups lists the enclosing groups and an empty Attr drops the attribute.
+// It is never called for group attributes themselves; their members are visited instead.
+type ReplaceAttrFunc func(groups []string, a slog.Attr) slog.Attr
+
+// ReplaceAttrHandler applies a ReplaceAttrFunc to every attribute before passing
+// records on, for handlers such as the OTel bridge that have no ReplaceAttr option.
+type ReplaceAttrHandler struct {
+	next    slog.Handler
+	replace ReplaceAttrFunc
+	groups  []string
+}
+
+// NewReplaceAttrHandler wraps handler so that replace is applied to every attribute
+func NewReplaceAttrHandler(handler slog.Handler, replace ReplaceAttrFunc) *ReplaceAttrHandler {
+	return &ReplaceAttrHandler{next: handler, replace: replace}
+}
+
+func (h *ReplaceAttrHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *ReplaceAttrHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a = h.replaceAttr(h.groups, a); !a.Equal(slog.Attr{}) {
+			out.AddAttrs(a)
+		}
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *ReplaceAttrHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	replaced := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a = h.replaceAttr(h.groups, a); !a.Equal(slog.Attr{}) {
+			replaced = append(replaced, a)
+		}
+	}
+	return &ReplaceAttrHandler{next: h.next.WithAttrs(replaced), replace: h.replace, groups: h.groups}
+}
+
+func (h *ReplaceAttrHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &ReplaceAttrHandler{
+		next:    h.next.WithGroup(name),
+		replace: h.replace,
+		groups:  append(slices.Clip(h.groups), name),
+	}
+}
+
+// replaceAttr applies the replace func, descending into group values
+func (h *ReplaceAttrHandler) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return h.replace(groups, a)
+	}
+
+	members := a.Value.Group()
+	if a.Key != "" {
+		groups = append(slices.Clip(groups), a.Key)
+	}
+	replaced := make([]slog.Attr, 0, len(members))
+	for _, member := range members {
+		if member = h.replaceAttr(groups, member); !member.Equal(slog.Attr{}) {
+			replaced = append(replaced, member)
+		}
+	}
+	if len(replaced) == 0 {
+		return slog.Attr{}
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(replaced...)}
+}
+
+// DropAttrs returns a ReplaceAttrFunc that removes attributes by key. A key
+// matches either the attribute's own name or its dotted path through groups,
+// e.g. "user_agent" or "http.user_agent".
+func DropAttrs(keys ...string) ReplaceAttrFunc {
+	drop := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		drop[key] = true
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if drop[a.Key] {
+			return slog.Attr{}
+		}
+		if len(groups) > 0 && drop[strings.Join(append(slices.Clip(groups), a.Key), ".")] {
+			return slog.Attr{}
+		}
+		return a
+	}
+}
+
+// NewLogSink wraps handler as a named sink with its own adjustable level and
+// the given attributes removed
+func NewLogSink(name string, handler slog.Handler, dropAttrs []string) slog.Handler {
+	if len(dropAttrs) > 0 {
+		handler = NewReplaceAttrHandler(handler, DropAttrs(dropAttrs...))
+	}
+	return NewLevelHandler(LogLevel(name), handler)
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFanoutHandlerWritesToAllSinks(t *testing.T) {
+	var console, otel bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	logger := slog.New(NewTraceHandler(NewFanoutHandler(
+		slog.NewTextHandler(&console, opts),
+		slog.NewJSONHandler(&otel, opts),
+	)))
+
+	logger.InfoContext(tracedContext(), "fanned out")
+
+	for name, buf := range map[string]*bytes.Buffer{"console": &console, "otel": &otel} {
+		if !strings.Contains(buf.String(), "fanned out") {
+			t.Errorf("%s sink should receive the record", name)
+		}
+		if !strings.Contains(buf.String(), "req-123") {
+			t.Errorf("%s sink should receive trace enrichment", name)
+		}
+	}
+}
+
+func TestLogSinkIndependentLevels(t *testing.T) {
+	var console, otel bytes.Buffer
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	SetLogLevel("test-console", slog.LevelWarn)
+	SetLogLevel("test-otel", slog.LevelDebug)
+
+	logger := slog.New(NewFanoutHandler(
+		NewLogSink("test-console", slog.NewTextHandler(&console, opts), nil),
+		NewLogSink("test-otel", slog.NewTextHandler(&otel, opts), nil),
+	))
+	logger.Debug("debug only")
+
+	if console.Len() != 0 {
+		t.Errorf("Console sink at WARN should drop debug records, got %q", console.String())
+	}
+	if !strings.Contains(otel.String(), "debug only") {
+		t.Error("OTel sink at DEBUG should receive debug records")
+	}
+}
+
+func TestLogSinkDropAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewLogSink("test-drop", slog.NewJSONHandler(&buf, nil), []string{"user_agent", "http.path"})
+	logger := slog.New(sink).With("user_agent", "curl")
+
+	logger.WithGroup("http").Info("request", "path", "/secret", "method", "GET")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := record["user_agent"]; ok {
+		t.Error("user_agent should be dropped from WithAttrs")
+	}
+	group, _ := record["http"].(map[string]any)
+	if _, ok := group["path"]; ok {
+		t.Error("http.path should be dropped by dotted path")
+	}
+	if group["method"] != "GET" {
+		t.Errorf("Other attributes should be kept, got %v", group)
+	}
+}
+
+func TestReplaceAttrHandlerNestedGroups(t *testing.T) {
+	var buf bytes.Buffer
+	upper := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() == slog.KindString {
+			a.Value = slog.StringValue(strings.ToUpper(a.Value.String()))
+		}
+		return a
+	}
+	logger := slog.New(NewReplaceAttrHandler(slog.NewJSONHandler(&buf, nil), upper))
+
+	logger.InfoContext(context.Background(), "msg", slog.Group("outer", slog.Group("inner", "k", "v")))
+
+	if !strings.Contains(buf.String(), `"inner":{"k":"V"}`) {
+		t.Errorf("Replace func should reach nested groups, got %s", buf.String())
+	}
+}
@@ -25,6 +25,7 @@ var (
 	httpRequestDuration      metric.Float64Histogram
 	httpRequestSize          metric.Int64Histogram
 	httpResponseSize         metric.Int64Histogram
+	httpResponseUncompressed metric.Int64Histogram
 	httpActiveRequests       metric.Int64UpDownCounter
 	observabilityInitialized = false
 )
@@ -34,13 +35,26 @@ type contextKey int
 
 const (
 	requestIDKey contextKey = iota
+	clientInfoKey
+	fidKey
+	cspNonceKey
+	cachePolicyKey
+	farcasterContextKey
+	logAttrsKey
 )
 
-// ObservabilityResponseWriter wraps http.ResponseWriter to capture metrics
+// uncompressedBodySizeKey records the response body size before content coding
+const uncompressedBodySizeKey = attribute.Key("http.response.body.uncompressed_size")
+
+// ObservabilityResponseWriter wraps http.ResponseWriter to capture metrics.
+// responseSize counts the bytes sent on the wire; when CompressionMiddleware
+// runs inside, uncompressedSize counts the bytes written by the handler.
 type ObservabilityResponseWriter struct {
 	http.ResponseWriter
-	statusCode   int
-	responseSize int64
+	statusCode       int
+	responseSize     int64
+	uncompressedSize int64
+	compressed       bool
 }
 
 func (w *ObservabilityResponseWriter) WriteHeader(code int) {
@@ -54,6 +68,47 @@ func (w *ObservabilityResponseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
+// Flush forwards to the underlying writer so streaming responses work through the wrapper
+func (w *ObservabilityResponseWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *ObservabilityResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// UncompressedSize returns the body size before compression, which equals
+// the wire size for responses sent without a content coding
+func (w *ObservabilityResponseWriter) UncompressedSize() int64 {
+	if w.compressed {
+		return w.uncompressedSize
+	}
+	return w.responseSize
+}
+
+func (w *ObservabilityResponseWriter) recordUncompressed(n int64) {
+	w.compressed = true
+	w.uncompressedSize += n
+	recordUncompressed(w.ResponseWriter, n)
+}
+
+// recordUncompressed reports n pre-compression bytes to the nearest
+// ObservabilityResponseWriter in the wrapper chain of w
+func recordUncompressed(w http.ResponseWriter, n int64) {
+	for {
+		if ow, ok := w.(*ObservabilityResponseWriter); ok {
+			ow.recordUncompressed(n)
+			return
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return
+		}
+		w = u.Unwrap()
+	}
+}
+
 // initObservabilityMetrics initializes metrics once
 func initObservabilityMetrics() error {
 	if observabilityInitialized {
@@ -99,6 +154,15 @@ func initObservabilityMetrics() error {
 		return err
 	}
 
+	httpResponseUncompressed, err = observabilityMeter.Int64Histogram(
+		"http.server.response.uncompressed_size",
+		metric.WithDescription("Size of HTTP server response bodies before compression"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
 	observabilityInitialized = true
 	return nil
 }
@@ -120,16 +184,19 @@ func ObservabilityMiddleware(next http.Handler) http.Handler {
 		}
 		ctx := ContextWithRequestID(r.Context(), requestID)
 
-		// Derive route template for low-cardinality span name/attributes
-		routeTemplate := r.URL.Path
+		// Derive route template for low-cardinality span name/attributes.
+		// Unmatched requests (404/405 handlers) have no route and use the method alone.
+		routeTemplate := ""
+		spanName := r.Method
 		if route := mux.CurrentRoute(r); route != nil {
 			if tmpl, err := route.GetPathTemplate(); err == nil {
 				routeTemplate = tmpl
+				spanName = r.Method + " " + tmpl
 			}
 		}
 
 		// Start tracing span
-		ctx, span := observabilityTracer.Start(ctx, r.Method+" "+routeTemplate,
+		ctx, span := observabilityTracer.Start(ctx, spanName,
 			trace.WithAttributes(
 				semconv.HTTPRequestMethodKey.String(r.Method),
 				semconv.URLPathKey.String(r.URL.Path),
@@ -147,7 +214,9 @@ func ObservabilityMiddleware(next http.Handler) http.Handler {
 		// Ensure Content-Length is non-negative
 		requestSize := max(r.ContentLength, 0)
 
-		// Increment active requests metric and ensure decrement
+		// Track the request for graceful drain and the active requests metric
+		inFlight.Add(1)
+		defer inFlight.Add(-1)
 		if httpActiveRequests != nil {
 			attrs := metric.WithAttributes(
 				semconv.HTTPRequestMethodKey.String(r.Method),
@@ -181,6 +250,7 @@ func ObservabilityMiddleware(next http.Handler) http.Handler {
 			append(commonAttrs,
 				semconv.HTTPRequestBodySizeKey.Int64(requestSize),
 				semconv.HTTPResponseBodySizeKey.Int64(wrapped.responseSize),
+				uncompressedBodySizeKey.Int64(wrapped.UncompressedSize()),
 			)...,
 		)
 
@@ -191,6 +261,7 @@ func ObservabilityMiddleware(next http.Handler) http.Handler {
 			httpRequestDuration.Record(ctx, duration.Seconds(), metricAttrs)
 			httpRequestSize.Record(ctx, requestSize, metricAttrs)
 			httpResponseSize.Record(ctx, wrapped.responseSize, metricAttrs)
+			httpResponseUncompressed.Record(ctx, wrapped.UncompressedSize(), metricAttrs)
 
 			// Decrement active requests
 			// handled by defer above
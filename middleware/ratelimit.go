@@ -0,0 +1,238 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+var (
+	rateLimitAllowed     metric.Int64Counter
+	rateLimitLimited     metric.Int64Counter
+	rateLimitMetricsOnce sync.Once
+)
+
+// rateLimitSweepInterval is how often idle buckets are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitKeyFunc derives the bucket key of a request
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitOptions configures a RateLimiter
+type RateLimitOptions struct {
+	// Name identifies the policy in metrics and logs
+	Name string
+	// Rate is the number of tokens added per second
+	Rate float64
+	// Burst is the bucket capacity
+	Burst int
+	// Key selects the bucket; defaults to DefaultRateLimitKey
+	Key RateLimitKeyFunc
+}
+
+// DefaultRateLimitKey keys requests by Farcaster ID, preferring the
+// authenticated one over the session's Mini App context, then by client IP
+func DefaultRateLimitKey(r *http.Request) string {
+	if fid, ok := FIDFromContext(r.Context()); ok {
+		return "fid:" + strconv.FormatUint(fid, 10)
+	}
+	if fc, ok := FarcasterContextFromContext(r.Context()); ok && fc.User.FID != 0 {
+		return "fid:" + strconv.FormatUint(fc.User.FID, 10)
+	}
+	return "ip:" + ClientIP(r)
+}
+
+// APIKeyRateLimitKey keys requests whose X-Api-Key is one of keys by that
+// key, so each integration gets its own bucket, and all others by
+// DefaultRateLimitKey.
+// Unknown keys are ignored, as inventing a new key per request would
+// otherwise get a fresh bucket every time.
+func APIKeyRateLimitKey(keys []string) RateLimitKeyFunc {
+	known := make(map[[sha256.Size]byte]bool, len(keys))
+	for _, key := range keys {
+		known[sha256.Sum256([]byte(key))] = true
+	}
+	return func(r *http.Request) string {
+		if apiKey := r.Header.Get("X-Api-Key"); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			if known[sum] {
+				return "key:" + hex.EncodeToString(sum[:8])
+			}
+		}
+		return DefaultRateLimitKey(r)
+	}
+}
+
+// RateLimiter enforces a token-bucket limit per key
+type RateLimiter struct {
+	opts      RateLimitOptions
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimitDecision is the outcome of taking a token
+type rateLimitDecision struct {
+	allowed   bool
+	remaining int
+	// retryAfter is the wait until the next token, reset the wait until the bucket is full
+	retryAfter time.Duration
+	reset      time.Duration
+}
+
+// NewRateLimiter creates a limiter for opts
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	if opts.Key == nil {
+		opts.Key = DefaultRateLimitKey
+	}
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	rateLimitMetricsOnce.Do(initRateLimitMetrics)
+	return &RateLimiter{opts: opts, buckets: make(map[string]*tokenBucket), now: time.Now}
+}
+
+func initRateLimitMetrics() {
+	var err error
+	rateLimitAllowed, err = observabilityMeter.Int64Counter(
+		"http.server.rate_limit.allowed",
+		metric.WithDescription("Requests admitted by a rate limit policy"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize rate limit metrics", "error", err)
+	}
+	rateLimitLimited, err = observabilityMeter.Int64Counter(
+		"http.server.rate_limit.limited",
+		metric.WithDescription("Requests rejected by a rate limit policy"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize rate limit metrics", "error", err)
+	}
+}
+
+// take removes a token from the bucket for key if one is available
+func (l *RateLimiter) take(key string) rateLimitDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	burst := float64(l.opts.Burst)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*l.opts.Rate)
+	b.last = now
+
+	d := rateLimitDecision{allowed: b.tokens >= 1}
+	if d.allowed {
+		b.tokens--
+	} else {
+		d.retryAfter = l.wait(1 - b.tokens)
+	}
+	d.remaining = int(b.tokens)
+	d.reset = l.wait(burst - b.tokens)
+	return d
+}
+
+// wait returns how long it takes to refill n tokens
+func (l *RateLimiter) wait(n float64) time.Duration {
+	if l.opts.Rate <= 0 {
+		return time.Hour
+	}
+	return time.Duration(n / l.opts.Rate * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely; callers hold l.mu
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.opts.Rate >= float64(l.opts.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware rejects requests over the limit with 429 and sets RateLimit-* headers
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := l.take(l.opts.Key(r))
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(l.opts.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+
+		attrs := metric.WithAttributes(attribute.String("rate_limit.policy", l.opts.Name))
+		if d.allowed {
+			if rateLimitAllowed != nil {
+				rateLimitAllowed.Add(r.Context(), 1, attrs)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if rateLimitLimited != nil {
+			rateLimitLimited.Add(r.Context(), 1, attrs)
+		}
+		retryAfter := ceilSeconds(d.retryAfter)
+		slog.WarnContext(r.Context(), "Rate limit exceeded", "policy", l.opts.Name, "retry_after", retryAfter)
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		writeRateLimited(w, r, retryAfter)
+	})
+}
+
+var rateLimitedFragment = template.Must(template.New("rate-limited").Parse(
+	`<div class="bg-yellow-50 border border-yellow-200 rounded-md p-4" role="alert">
+		<p class="text-gray-700">Slow down! Try again in <strong class="text-yellow-700">{{.}}s</strong>.</p>
+	</div>`))
+
+// writeRateLimited renders an HTMX fragment or a plain-text 429 response
+func writeRateLimited(w http.ResponseWriter, r *http.Request, retryAfter int) {
+	if !IsHTMX(r) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	RetargetToast(w)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	rateLimitedFragment.Execute(w, retryAfter)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// ContextWithFID records the authenticated Farcaster ID of the request
+func ContextWithFID(ctx context.Context, fid uint64) context.Context {
+	return context.WithValue(ctx, fidKey, fid)
+}
+
+// FIDFromContext returns the authenticated Farcaster ID, if any
+func FIDFromContext(ctx context.Context) (uint64, bool) {
+	fid, ok := ctx.Value(fidKey).(uint64)
+	return fid, ok
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"hello-world/models"
+)
+
+func newTestLimiter(rate float64, burst int) (*RateLimiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	l := NewRateLimiter(RateLimitOptions{Name: "test", Rate: rate, Burst: burst})
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestRateLimiterTokenBucket(t *testing.T) {
+	l, now := newTestLimiter(1, 2)
+
+	if d := l.take("a"); !d.allowed || d.remaining != 1 {
+		t.Errorf("First request should be allowed with 1 remaining, got %+v", d)
+	}
+	if d := l.take("a"); !d.allowed || d.remaining != 0 {
+		t.Errorf("Second request should be allowed with 0 remaining, got %+v", d)
+	}
+	d := l.take("a")
+	if d.allowed {
+		t.Error("Third request should be limited")
+	}
+	if d.retryAfter != time.Second {
+		t.Errorf("Expected retry after 1s, got %v", d.retryAfter)
+	}
+
+	// Other keys have their own bucket
+	if d := l.take("b"); !d.allowed {
+		t.Error("Different key should not be limited")
+	}
+
+	// Tokens refill over time
+	*now = now.Add(time.Second)
+	if d := l.take("a"); !d.allowed {
+		t.Error("Request should be allowed after refill")
+	}
+}
+
+func TestRateLimiterSweepsIdleBuckets(t *testing.T) {
+	l, now := newTestLimiter(1, 1)
+	l.take("a")
+	*now = now.Add(2 * rateLimitSweepInterval)
+	l.take("b")
+
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("Refilled idle bucket should be swept")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	l, _ := newTestLimiter(0.5, 1)
+	handler := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/click", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", rr.Code)
+	}
+	if rr.Header().Get("RateLimit-Limit") != "1" || rr.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected RateLimit headers: %v", rr.Header())
+	}
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/click", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected Retry-After 2, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	req := httptest.NewRequest("POST", "/api/click", nil)
+	req.Header.Set("HX-Request", "true")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), "Slow down!") {
+		t.Errorf("Expected HTMX fragment, got %q", rr.Body.String())
+	}
+}
+
+func TestDefaultRateLimitKey(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if key := DefaultRateLimitKey(req); key != "ip:192.0.2.1" {
+		t.Errorf("Expected IP key, got %s", key)
+	}
+
+	req.Header.Set("X-Api-Key", "secret")
+	if key := DefaultRateLimitKey(req); key != "ip:192.0.2.1" {
+		t.Errorf("Expected API keys to be ignored, got %s", key)
+	}
+
+	req = req.WithContext(WithFarcasterContext(req.Context(), &models.MiniAppContext{User: models.MiniAppUser{FID: 3}}))
+	if key := DefaultRateLimitKey(req); key != "fid:3" {
+		t.Errorf("Expected the Mini App context FID, got %s", key)
+	}
+
+	req = req.WithContext(ContextWithFID(req.Context(), 42))
+	if key := DefaultRateLimitKey(req); key != "fid:42" {
+		t.Errorf("Expected the authenticated FID to win, got %s", key)
+	}
+}
+
+func TestAPIKeyRateLimitKey(t *testing.T) {
+	keyFunc := APIKeyRateLimitKey([]string{"secret"})
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	if key := keyFunc(req); !strings.HasPrefix(key, "key:") || strings.Contains(key, "secret") {
+		t.Errorf("Expected hashed API key, got %s", key)
+	}
+
+	// Invented keys share the client's IP bucket
+	req.Header.Set("X-Api-Key", "random-123")
+	if key := keyFunc(req); key != "ip:192.0.2.1" {
+		t.Errorf("Expected unknown API keys to fall back to the IP, got %s", key)
+	}
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	httpPanics         metric.Int64Counter
+	recoveryMetricOnce sync.Once
+)
+
+// recoveryResponseWriter tracks whether the response has started
+type recoveryResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *recoveryResponseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoveryResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recoveryResponseWriter) Flush() {
+	w.wroteHeader = true
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (w *recoveryResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// handlerPanic carries a panic re-raised on another goroutine, such as by
+// TimeoutMiddleware, with the stack of the goroutine that panicked
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// RecoveryMiddleware recovers from handler panics: the stack is recorded as a
+// span event with an error status, logged with the request ID, counted, and
+// errorPage renders the 500 response if nothing was written yet.
+func RecoveryMiddleware(errorPage http.Handler) func(http.Handler) http.Handler {
+	recoveryMetricOnce.Do(func() {
+		var err error
+		httpPanics, err = observabilityMeter.Int64Counter(
+			"http.server.panics",
+			metric.WithDescription("Panics recovered in HTTP handlers"),
+			metric.WithUnit("{panic}"),
+		)
+		if err != nil {
+			slog.Warn("Failed to initialize panic metric", "error", err)
+		}
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := &recoveryResponseWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// Let net/http handle deliberate aborts
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				ctx := r.Context()
+				stack := string(debug.Stack())
+				if hp, ok := rec.(*handlerPanic); ok {
+					rec, stack = hp.value, string(hp.stack)
+				}
+				message := fmt.Sprint(rec)
+
+				span := trace.SpanFromContext(ctx)
+				span.AddEvent("exception", trace.WithAttributes(
+					semconv.ExceptionType(fmt.Sprintf("%T", rec)),
+					semconv.ExceptionMessage(message),
+					semconv.ExceptionStacktrace(stack),
+					attribute.Bool("exception.escaped", false),
+				))
+				span.SetStatus(codes.Error, "panic: "+message)
+
+				slog.ErrorContext(ctx, "Panic recovered",
+					"panic", message,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"stack", stack,
+				)
+
+				if httpPanics != nil {
+					httpPanics.Add(ctx, 1, metric.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)))
+				}
+
+				if wrapped.wroteHeader {
+					// Too late for an error page; the client sees a truncated response
+					return
+				}
+				errorPage.ServeHTTP(w, r)
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
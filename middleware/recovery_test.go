@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var errorPage = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("error page"))
+})
+
+func TestRecoveryMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	handler := RecoveryMiddleware(errorPage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "GET /")
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	span.End()
+
+	if rr.Code != http.StatusInternalServerError || rr.Body.String() != "error page" {
+		t.Errorf("Expected error page with 500, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	ended := recorder.Ended()[0]
+	if ended.Status().Code != codes.Error {
+		t.Errorf("Expected span status Error, got %v", ended.Status())
+	}
+	if len(ended.Events()) != 1 || ended.Events()[0].Name != "exception" {
+		t.Fatalf("Expected one exception event, got %v", ended.Events())
+	}
+	var hasStack bool
+	for _, kv := range ended.Events()[0].Attributes {
+		if kv.Key == "exception.stacktrace" && kv.Value.AsString() != "" {
+			hasStack = true
+		}
+	}
+	if !hasStack {
+		t.Error("Exception event should include the stack trace")
+	}
+}
+
+func TestRecoveryMiddlewareAfterWrite(t *testing.T) {
+	handler := RecoveryMiddleware(errorPage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("late boom")
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Body.String() != "partial" {
+		t.Errorf("Error page must not be appended to a started response, got %q", rr.Body.String())
+	}
+}
+
+func TestRecoveryMiddlewareAbortHandler(t *testing.T) {
+	handler := RecoveryMiddleware(errorPage)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to be re-raised, got %v", rec)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
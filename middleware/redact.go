@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Redacted replaces values of sensitive keys
+const Redacted = "[REDACTED]"
+
+// DefaultRedactKeys are attribute keys whose values are always replaced.
+// Matching is case-insensitive on the full key or its last dotted segment,
+// so "authorization" also covers "http.request.header.authorization".
+var DefaultRedactKeys = []string{
+	// Credentials
+	"authorization", "cookie", "set-cookie", "token", "access_token", "refresh_token",
+	"id_token", "password", "secret", "api_key", "apikey", "x-api-key",
+	// Farcaster identity data linked to an FID
+	"username", "display_name", "pfp_url", "custody_address", "verifications", "email",
+}
+
+// DefaultQueryKeys are URL-valued attribute keys whose query strings are removed
+var DefaultQueryKeys = []string{"url.full", "url", "http.url", "http.target", "referer", "referrer"}
+
+// ValueRule scrubs matches of Pattern inside string values
+type ValueRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// DefaultValueRules scrub emails, Ethereum addresses and JWTs wherever they appear
+var DefaultValueRules = []ValueRule{
+	{
+		Name:        "jwt",
+		Pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		Replacement: "[REDACTED_JWT]",
+	},
+	{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Replacement: "[REDACTED_EMAIL]",
+	},
+	{
+		Name:        "eth_address",
+		Pattern:     regexp.MustCompile(`\b0x[0-9a-fA-F]{40}\b`),
+		Replacement: "[REDACTED_ETH_ADDRESS]",
+	},
+}
+
+// RedactorOptions configures a Redactor; nil slices select the defaults
+type RedactorOptions struct {
+	// Keys are redacted in addition to DefaultRedactKeys
+	Keys []string
+	// QueryKeys replace DefaultQueryKeys when set
+	QueryKeys []string
+	// ValueRules replace DefaultValueRules when set
+	ValueRules []ValueRule
+}
+
+// Redactor removes sensitive data from log attributes and span attributes
+type Redactor struct {
+	keys       map[string]bool
+	queryKeys  map[string]bool
+	valueRules []ValueRule
+}
+
+// NewRedactor creates a Redactor from opts
+func NewRedactor(opts RedactorOptions) *Redactor {
+	r := &Redactor{
+		keys:       lowerSet(append(append([]string{}, DefaultRedactKeys...), opts.Keys...)),
+		queryKeys:  lowerSet(DefaultQueryKeys),
+		valueRules: DefaultValueRules,
+	}
+	if opts.QueryKeys != nil {
+		r.queryKeys = lowerSet(opts.QueryKeys)
+	}
+	if opts.ValueRules != nil {
+		r.valueRules = opts.ValueRules
+	}
+	return r
+}
+
+func lowerSet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[strings.ToLower(key)] = true
+	}
+	return set
+}
+
+// matches reports whether key or its last dotted segment is in set
+func matches(set map[string]bool, key string) bool {
+	key = strings.ToLower(key)
+	if set[key] {
+		return true
+	}
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		return set[key[i+1:]]
+	}
+	return false
+}
+
+// RedactValue returns the redacted form of a string value stored under key
+func (r *Redactor) RedactValue(key, value string) string {
+	if matches(r.keys, key) {
+		return Redacted
+	}
+	if strings.EqualFold(key, "url.query") {
+		return ""
+	}
+	if matches(r.queryKeys, key) {
+		value = StripQuery(value)
+	}
+	return r.Scrub(value)
+}
+
+// Scrub applies the value rules to s
+func (r *Redactor) Scrub(s string) string {
+	for _, rule := range r.valueRules {
+		s = rule.Pattern.ReplaceAllString(s, rule.Replacement)
+	}
+	return s
+}
+
+// StripQuery removes the query string and fragment from a URL or request target
+func StripQuery(s string) string {
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// ReplaceAttr redacts a log attribute; use it with NewReplaceAttrHandler.
+// Every member of a group named by a sensitive key is redacted. Errors and
+// Stringers are scrubbed as text, and maps and structs become groups whose
+// members are redacted like attributes; other values are left as they are.
+func (r *Redactor) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if matches(r.keys, a.Key) || slices.ContainsFunc(groups, func(group string) bool { return matches(r.keys, group) }) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.RedactValue(a.Key, a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.Scrub(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, r.RedactValue(a.Key, v.String()))
+		}
+		if len(groups) < maxRedactDepth {
+			if members, ok := fieldAttrs(a.Value.Any()); ok {
+				groups = append(slices.Clip(groups), a.Key)
+				for i, member := range members {
+					members[i] = r.ReplaceAttr(groups, member)
+					// Map keys are data too
+					members[i].Key = r.Scrub(members[i].Key)
+				}
+				return slog.Attr{Key: a.Key, Value: slog.GroupValue(members...)}
+			}
+		}
+	}
+	return a
+}
+
+// maxRedactDepth bounds how deep ReplaceAttr descends into nested values
+const maxRedactDepth = 8
+
+// fieldAttrs returns the entries of a map with string keys, sorted by key, or
+// the exported fields of a struct named as encoding/json would, so they can be
+// redacted by name. Empty values are not expanded, as an empty group is dropped.
+func fieldAttrs(value any) ([]slog.Attr, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		attrs := make([]slog.Attr, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			attrs = append(attrs, slog.Any(key.String(), v.MapIndex(key).Interface()))
+		}
+		slices.SortFunc(attrs, func(a, b slog.Attr) int { return strings.Compare(a.Key, b.Key) })
+		return attrs, len(attrs) > 0
+	case v.Kind() == reflect.Struct:
+		t := v.Type()
+		attrs := make([]slog.Attr, 0, t.NumField())
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			attrs = append(attrs, slog.Any(name, v.Field(i).Interface()))
+		}
+		return attrs, len(attrs) > 0
+	}
+	return nil, false
+}
+
+// Handler wraps handler so every record passes through the redactor
+func (r *Redactor) Handler(handler slog.Handler) slog.Handler {
+	return NewReplaceAttrHandler(handler, r.ReplaceAttr)
+}
+
+// RedactAttributes returns a redacted copy of span attributes
+func (r *Redactor) RedactAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	out := make([]attribute.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		key := string(kv.Key)
+		switch {
+		case matches(r.keys, key):
+			out[i] = kv.Key.String(Redacted)
+		case kv.Value.Type() == attribute.STRING:
+			out[i] = kv.Key.String(r.RedactValue(key, kv.Value.AsString()))
+		case kv.Value.Type() == attribute.STRINGSLICE:
+			values := kv.Value.AsStringSlice()
+			for j, v := range values {
+				values[j] = r.RedactValue(key, v)
+			}
+			out[i] = kv.Key.StringSlice(values)
+		default:
+			out[i] = kv
+		}
+	}
+	return out
+}
+
+// RedactingSpanProcessor redacts span and event attributes before passing
+// ended spans to the next processor, so exporters never see raw values
+type RedactingSpanProcessor struct {
+	next     sdktrace.SpanProcessor
+	redactor *Redactor
+}
+
+// NewRedactingSpanProcessor wraps next with redaction
+func NewRedactingSpanProcessor(next sdktrace.SpanProcessor, redactor *Redactor) *RedactingSpanProcessor {
+	return &RedactingSpanProcessor{next: next, redactor: redactor}
+}
+
+func (p *RedactingSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *RedactingSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	events := s.Events()
+	redactedEvents := make([]sdktrace.Event, len(events))
+	for i, event := range events {
+		event.Attributes = p.redactor.RedactAttributes(event.Attributes)
+		redactedEvents[i] = event
+	}
+	p.next.OnEnd(redactedSpan{
+		ReadOnlySpan: s,
+		attrs:        p.redactor.RedactAttributes(s.Attributes()),
+		events:       redactedEvents,
+	})
+}
+
+func (p *RedactingSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+func (p *RedactingSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// redactedSpan overrides the attribute accessors of an ended span
+type redactedSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs  []attribute.KeyValue
+	events []sdktrace.Event
+}
+
+func (s redactedSpan) Attributes() []attribute.KeyValue { return s.attrs }
+
+func (s redactedSpan) Events() []sdktrace.Event { return s.events }
@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedactorRedactValue(t *testing.T) {
+	r := NewRedactor(RedactorOptions{Keys: []string{"fid_secret"}})
+
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"authorization", "Bearer abc", Redacted},
+		{"http.request.header.Cookie", "session=1", Redacted},
+		{"fid_secret", "42", Redacted},
+		{"message", "contact alice@example.com", "contact [REDACTED_EMAIL]"},
+		{"wallet", "sent to 0x52908400098527886E0F7030069857D2E4169EE7", "sent to [REDACTED_ETH_ADDRESS]"},
+		{"header", "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_part", "[REDACTED_JWT]"},
+		{"url.full", "https://example.com/cb?code=abc#frag", "https://example.com/cb"},
+		{"url.query", "code=abc", ""},
+		{"url.path", "/api/time", "/api/time"},
+	}
+
+	for _, test := range tests {
+		if got := r.RedactValue(test.key, test.value); got != test.want {
+			t.Errorf("RedactValue(%q, %q) = %q, want %q", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestRedactorHandlerNestedGroups(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewRedactor(RedactorOptions{})
+	logger := slog.New(NewTraceHandler(r.Handler(slog.NewJSONHandler(&buf, nil))))
+
+	logger.With("token", "t0p").WithGroup("req").Info("login",
+		slog.Group("headers", "Authorization", "Bearer x", "accept", "text/html"),
+		slog.Group("user", slog.Group("profile", "email", "bob@example.com", "bio", "mail me at bob@example.com")),
+		"error", errors.New("lookup failed for carol@example.com"),
+		slog.Group("authorization", "value", "Bearer t0k", "scheme", "bearer"),
+		"profile", struct{ Bio string }{"mail me at dave@example.com"},
+		"session", &testSession{ID: "s1", Token: "t0k3n", Owner: testOwner{Email: "frank@example.com", FID: 3}},
+		"counts", map[string]string{"erin@example.com": "3"},
+		"url", &url.URL{Scheme: "https", Host: "example.com", RawQuery: "token=x"},
+	)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["token"] != Redacted {
+		t.Errorf("WithAttrs token should be redacted, got %v", record["token"])
+	}
+
+	req := record["req"].(map[string]any)
+	headers := req["headers"].(map[string]any)
+	if headers["Authorization"] != Redacted || headers["accept"] != "text/html" {
+		t.Errorf("Unexpected headers group: %v", headers)
+	}
+	profile := req["user"].(map[string]any)["profile"].(map[string]any)
+	if profile["email"] != Redacted {
+		t.Errorf("Nested email key should be redacted, got %v", profile["email"])
+	}
+	if profile["bio"] != "mail me at [REDACTED_EMAIL]" {
+		t.Errorf("Nested value should be scrubbed, got %v", profile["bio"])
+	}
+	if strings.Contains(buf.String(), "carol@example.com") {
+		t.Error("Error values should be scrubbed")
+	}
+	authorization := req["authorization"].(map[string]any)
+	if authorization["value"] != Redacted || authorization["scheme"] != Redacted {
+		t.Errorf("Members of a sensitive group should be redacted, got %v", authorization)
+	}
+	for _, email := range []string{"dave@example.com", "erin@example.com"} {
+		if strings.Contains(buf.String(), email) {
+			t.Errorf("Struct and map values should be scrubbed, found %s", email)
+		}
+	}
+	session := req["session"].(map[string]any)
+	if session["id"] != "s1" || session["token"] != Redacted {
+		t.Errorf("Sensitive struct fields should be redacted by name, got %v", session)
+	}
+	if owner := session["owner"].(map[string]any); owner["email"] != Redacted || owner["fid"] != float64(3) {
+		t.Errorf("Nested struct fields should be redacted by name, got %v", owner)
+	}
+	if _, ok := req["error"].(string); !ok {
+		t.Errorf("Errors should be logged as their scrubbed message, got %v", req["error"])
+	}
+	if req["url"] != "https://example.com" {
+		t.Errorf("Stringer URLs should lose their query, got %v", req["url"])
+	}
+}
+
+type testSession struct {
+	ID    string    `json:"id"`
+	Token string    `json:"token"`
+	Owner testOwner `json:"owner"`
+}
+
+type testOwner struct {
+	Email string `json:"email"`
+	FID   uint64 `json:"fid"`
+}
+
+func TestRedactingSpanProcessor(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(NewRedactingSpanProcessor(recorder, NewRedactor(RedactorOptions{}))),
+	)
+	defer provider.Shutdown(context.Background())
+
+	_, span := provider.Tracer("test").Start(context.Background(), "GET /")
+	span.SetAttributes(
+		attribute.String("url.path", "/u/dave@example.com"),
+		attribute.String("http.request.header.authorization", "Bearer x"),
+		attribute.Int("http.response.status_code", 200),
+	)
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.message", "no user eve@example.com")))
+	span.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("Expected 1 ended span, got %d", len(ended))
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range ended[0].Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if got := attrs["url.path"].AsString(); got != "/u/[REDACTED_EMAIL]" {
+		t.Errorf("url.path should be scrubbed, got %q", got)
+	}
+	if got := attrs["http.request.header.authorization"].AsString(); got != Redacted {
+		t.Errorf("authorization should be redacted, got %q", got)
+	}
+	if got := attrs["http.response.status_code"].AsInt64(); got != 200 {
+		t.Errorf("Non-string attributes should be kept, got %d", got)
+	}
+	if len(ended[0].Events()) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(ended[0].Events()))
+	}
+	for _, event := range ended[0].Events() {
+		for _, kv := range event.Attributes {
+			if strings.Contains(kv.Value.Emit(), "@example.com") {
+				t.Errorf("Event attribute %s should be scrubbed, got %q", kv.Key, kv.Value.Emit())
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// traceSampler is the process-wide sampler installed on the tracer provider
+var traceSampler = NewDynamicSampler(1)
+
+// DynamicSampler is a ParentBased(TraceIDRatioBased) sampler whose ratio can be swapped at runtime
+type DynamicSampler struct {
+	current atomic.Pointer[ratioSampler]
+}
+
+type ratioSampler struct {
+	ratio   float64
+	sampler sdktrace.Sampler
+}
+
+// NewDynamicSampler creates a sampler that keeps ratio of root traces
+func NewDynamicSampler(ratio float64) *DynamicSampler {
+	s := &DynamicSampler{}
+	s.SetRatio(ratio)
+	return s
+}
+
+// TraceSampler returns the process-wide dynamic sampler
+func TraceSampler() *DynamicSampler {
+	return traceSampler
+}
+
+// SetRatio clamps ratio to [0, 1], swaps it in and returns the previous ratio
+func (s *DynamicSampler) SetRatio(ratio float64) float64 {
+	ratio = min(max(ratio, 0), 1)
+	next := &ratioSampler{
+		ratio:   ratio,
+		sampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)),
+	}
+	if old := s.current.Swap(next); old != nil {
+		return old.ratio
+	}
+	return ratio
+}
+
+// Ratio returns the current sampling ratio
+func (s *DynamicSampler) Ratio() float64 {
+	return s.current.Load().ratio
+}
+
+func (s *DynamicSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return s.current.Load().sampler.ShouldSample(p)
+}
+
+func (s *DynamicSampler) Description() string {
+	return fmt.Sprintf("Dynamic{%s}", s.current.Load().sampler.Description())
+}
+
+// ChangeSampleRatio sets the ratio of the process-wide sampler and audits the change
+func ChangeSampleRatio(ctx context.Context, ratio float64, source string) {
+	old := traceSampler.SetRatio(ratio)
+	Logger(AuditLogger).InfoContext(ctx, "Trace sample ratio changed",
+		"old_ratio", old,
+		"new_ratio", traceSampler.Ratio(),
+		"source", source,
+	)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestDynamicSamplerSetRatio(t *testing.T) {
+	s := NewDynamicSampler(0.5)
+	if s.Ratio() != 0.5 {
+		t.Errorf("Expected ratio 0.5, got %v", s.Ratio())
+	}
+
+	old := s.SetRatio(2)
+	if old != 0.5 {
+		t.Errorf("Expected previous ratio 0.5, got %v", old)
+	}
+	if s.Ratio() != 1 {
+		t.Errorf("Expected ratio clamped to 1, got %v", s.Ratio())
+	}
+
+	s.SetRatio(-1)
+	if s.Ratio() != 0 {
+		t.Errorf("Expected ratio clamped to 0, got %v", s.Ratio())
+	}
+}
+
+func TestDynamicSamplerDecisions(t *testing.T) {
+	s := NewDynamicSampler(0)
+	params := sdktrace.SamplingParameters{TraceID: trace.TraceID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}
+
+	if got := s.ShouldSample(params).Decision; got != sdktrace.Drop {
+		t.Errorf("Expected Drop at ratio 0, got %v", got)
+	}
+
+	s.SetRatio(1)
+	if got := s.ShouldSample(params).Decision; got != sdktrace.RecordAndSample {
+		t.Errorf("Expected RecordAndSample at ratio 1, got %v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	var nonce string
+	handler := SecurityHeadersMiddleware(SecurityHeadersOptions{HSTSMaxAge: time.Hour})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce = CSPNonce(r.Context())
+		}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if nonce == "" {
+		t.Fatal("Handler should see a CSP nonce in its context")
+	}
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'nonce-"+nonce+"'") {
+		t.Errorf("CSP should contain the request nonce, got %q", csp)
+	}
+	if !strings.Contains(csp, "frame-ancestors 'self' https://farcaster.xyz") {
+		t.Errorf("CSP should allow Farcaster clients to frame the app, got %q", csp)
+	}
+	if !strings.Contains(csp, "report-uri "+CSPReportPath) {
+		t.Errorf("CSP should report violations, got %q", csp)
+	}
+	for _, header := range []string{"Referrer-Policy", "Permissions-Policy", "X-Content-Type-Options"} {
+		if rr.Header().Get(header) == "" {
+			t.Errorf("Expected %s header", header)
+		}
+	}
+	if rr.Header().Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS should not be sent over plain HTTP")
+	}
+
+	// Each request gets a fresh nonce
+	first := nonce
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if nonce == first {
+		t.Error("Nonces should differ between requests")
+	}
+}
+
+func TestSecurityHeadersHSTSAndReportOnly(t *testing.T) {
+	opts := SecurityHeadersOptions{ReportOnly: true, HSTSMaxAge: time.Hour, FrameAncestors: []string{"https://example.com"}}
+	handler := SecurityHeadersMiddleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(ContextWithClientInfo(req.Context(), ClientInfo{Scheme: "https"}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Security-Policy") != "" {
+		t.Error("Report-only mode should not enforce the policy")
+	}
+	if !strings.Contains(rr.Header().Get("Content-Security-Policy-Report-Only"), "frame-ancestors https://example.com") {
+		t.Errorf("Expected report-only policy with custom frame ancestors, got %q", rr.Header().Get("Content-Security-Policy-Report-Only"))
+	}
+	if rr.Header().Get("Strict-Transport-Security") != "max-age=3600; includeSubDomains" {
+		t.Errorf("Expected HSTS over HTTPS, got %q", rr.Header().Get("Strict-Transport-Security"))
+	}
+}
@@ -3,25 +3,59 @@ package middleware
 import (
 	"context"
 	"log/slog"
+	"slices"
 
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
-// TraceHandler wraps an slog.Handler to automatically inject trace information
+// TraceHandler wraps an slog.Handler to automatically inject trace information.
+// Groups and attributes added through WithGroup/WithAttrs are kept on the
+// TraceHandler itself so that trace_id, span_id and request_id always stay at
+// the top level of the record, and so derived loggers keep the enrichment.
 type TraceHandler struct {
-	slog.Handler
+	next slog.Handler
+	goas []groupOrAttrs
+}
+
+// groupOrAttrs holds either a group name or a list of attributes
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
 }
 
 // NewTraceHandler creates a new TraceHandler that wraps the provided handler
 func NewTraceHandler(handler slog.Handler) *TraceHandler {
-	return &TraceHandler{Handler: handler}
+	return &TraceHandler{next: handler}
+}
+
+func (h *TraceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
 }
 
 // Handle processes log records and automatically adds trace information from context
 func (h *TraceHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	// Nest record attributes inside the groups opened on this handler
+	for i := len(h.goas) - 1; i >= 0; i-- {
+		if goa := h.goas[i]; goa.group != "" {
+			attrs = []slog.Attr{{Key: goa.group, Value: slog.GroupValue(attrs...)}}
+		} else {
+			attrs = append(slices.Clip(goa.attrs), attrs...)
+		}
+	}
+
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(attrs...)
+
 	// Automatically extract trace data from context
 	if span := trace.SpanFromContext(ctx); span.SpanContext().IsValid() {
-		r.AddAttrs(
+		out.AddAttrs(
 			slog.String("trace_id", span.SpanContext().TraceID().String()),
 			slog.String("span_id", span.SpanContext().SpanID().String()),
 		)
@@ -29,10 +63,38 @@ func (h *TraceHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// Extract request ID if present
 	if reqID := getRequestID(ctx); reqID != "" {
-		r.AddAttrs(slog.String("request_id", reqID))
+		out.AddAttrs(slog.String("request_id", reqID))
+	}
+
+	// Attributes describing the request's session, e.g. the Farcaster client context
+	if attrs, ok := ctx.Value(logAttrsKey).([]slog.Attr); ok {
+		out.AddAttrs(attrs...)
 	}
 
-	return h.Handler.Handle(ctx, r)
+	return h.next.Handle(ctx, out)
+}
+
+func (h *TraceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+func (h *TraceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *TraceHandler) withGroupOrAttrs(goa groupOrAttrs) *TraceHandler {
+	return &TraceHandler{next: h.next, goas: append(slices.Clip(h.goas), goa)}
+}
+
+// RequestIDFromContext returns the request ID assigned by ObservabilityMiddleware, or ""
+func RequestIDFromContext(ctx context.Context) string {
+	return getRequestID(ctx)
 }
 
 // getRequestID extracts request ID from context
@@ -44,3 +106,18 @@ func getRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// ContextWithLogAttrs adds attributes that TraceHandler appends to every record logged with ctx
+func ContextWithLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(logAttrsKey).([]slog.Attr)
+	return context.WithValue(ctx, logAttrsKey, append(slices.Clip(existing), attrs...))
+}
+
+// Traceparent returns the W3C traceparent of the span in ctx, or "" without a
+// valid span. Pages render it so browser telemetry can be correlated with the
+// server trace that produced them.
+func Traceparent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
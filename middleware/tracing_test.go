@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func tracedContext() context.Context {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+	return ContextWithRequestID(ctx, "req-123")
+}
+
+func TestTraceHandlerAddsTraceAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTraceHandler(slog.NewJSONHandler(&buf, nil)))
+
+	logger.InfoContext(tracedContext(), "hello")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["trace_id"] != "0102030405060708090a0b0c0d0e0f10" {
+		t.Errorf("Expected trace_id, got %v", record["trace_id"])
+	}
+	if record["span_id"] != "0102030405060708" {
+		t.Errorf("Expected span_id, got %v", record["span_id"])
+	}
+	if record["request_id"] != "req-123" {
+		t.Errorf("Expected request_id, got %v", record["request_id"])
+	}
+}
+
+func TestTraceHandlerWithGroupKeepsEnrichment(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewTraceHandler(slog.NewJSONHandler(&buf, nil)))
+
+	// Derived loggers must remain TraceHandlers
+	derived := logger.With("component", "test").WithGroup("http")
+	if _, ok := derived.Handler().(*TraceHandler); !ok {
+		t.Fatalf("Expected derived handler to be *TraceHandler, got %T", derived.Handler())
+	}
+
+	derived.InfoContext(tracedContext(), "hello", "status", 200)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["component"] != "test" {
+		t.Errorf("Expected component attribute at top level, got %v", record["component"])
+	}
+	group, ok := record["http"].(map[string]any)
+	if !ok || group["status"] != float64(200) {
+		t.Errorf("Expected status inside http group, got %v", record["http"])
+	}
+	// Trace attributes stay top-level rather than being nested in the group
+	if record["trace_id"] == nil || record["request_id"] != "req-123" {
+		t.Errorf("Expected top-level trace attributes, got %v", record)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	want := "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"
+	if got := Traceparent(tracedContext()); got != want {
+		t.Errorf("Traceparent = %q, want %q", got, want)
+	}
+	if got := Traceparent(context.Background()); got != "" {
+		t.Errorf("Expected no traceparent without a span, got %q", got)
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "net/http"
+
+// AppError is an error with an HTTP status and a message that is safe to show users.
+// The wrapped Err carries internal detail for logs only.
+type AppError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewAppError creates an AppError; an empty message defaults to the status text
+func NewAppError(status int, message string, err error) *AppError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &AppError{Status: status, Message: message, Err: err}
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
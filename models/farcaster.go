@@ -0,0 +1,190 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Mini App launch locations reported in MiniAppContext.Location.Type
+const (
+	LocationCastEmbed    = "cast_embed"
+	LocationCastShare    = "cast_share"
+	LocationNotification = "notification"
+	LocationLauncher     = "launcher"
+	LocationChannel      = "channel"
+	LocationOpenMiniApp  = "open_miniapp"
+)
+
+// Limits applied by MiniAppContext.Validate
+const (
+	maxShortField = 256
+	maxLongField  = 2048
+	maxInset      = 10000
+)
+
+// MiniAppContext is the subset of the Farcaster Mini App SDK's sdk.context that
+// the server keeps. It is reported by the client and unsigned, so it describes
+// the session for diagnostics and must not be used for authentication.
+// Notification tokens in client.notificationDetails are deliberately not modelled.
+type MiniAppContext struct {
+	User     MiniAppUser      `json:"user"`
+	Location *MiniAppLocation `json:"location,omitempty"`
+	Client   MiniAppClient    `json:"client"`
+	Features *MiniAppFeatures `json:"features,omitempty"`
+}
+
+// MiniAppUser is the Farcaster user viewing the Mini App
+type MiniAppUser struct {
+	FID         uint64 `json:"fid"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	PfpURL      string `json:"pfpUrl,omitempty"`
+}
+
+// MiniAppLocation describes where the Mini App was opened from; the fields
+// besides Type are set depending on it
+type MiniAppLocation struct {
+	Type           string               `json:"type"`
+	Embed          string               `json:"embed,omitempty"`
+	Cast           *MiniAppCast         `json:"cast,omitempty"`
+	Notification   *MiniAppNotification `json:"notification,omitempty"`
+	Channel        *MiniAppChannel      `json:"channel,omitempty"`
+	ReferrerDomain string               `json:"referrerDomain,omitempty"`
+}
+
+// MiniAppCast is the cast a Mini App was embedded in or shared from
+type MiniAppCast struct {
+	Author     MiniAppUser `json:"author"`
+	Hash       string      `json:"hash"`
+	Text       string      `json:"text,omitempty"`
+	ChannelKey string      `json:"channelKey,omitempty"`
+}
+
+// MiniAppNotification is the notification the user tapped to open the Mini App
+type MiniAppNotification struct {
+	NotificationID string `json:"notificationId"`
+	Title          string `json:"title,omitempty"`
+	Body           string `json:"body,omitempty"`
+}
+
+// MiniAppChannel is the channel the Mini App was opened from
+type MiniAppChannel struct {
+	Key      string `json:"key"`
+	Name     string `json:"name,omitempty"`
+	ImageURL string `json:"imageUrl,omitempty"`
+}
+
+// MiniAppClient describes the Farcaster client hosting the Mini App
+type MiniAppClient struct {
+	PlatformType   string          `json:"platformType,omitempty"`
+	ClientFID      uint64          `json:"clientFid"`
+	Added          bool            `json:"added"`
+	SafeAreaInsets *SafeAreaInsets `json:"safeAreaInsets,omitempty"`
+}
+
+// SafeAreaInsets are the CSS pixel insets the Mini App should keep clear on mobile
+type SafeAreaInsets struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+	Right  float64 `json:"right"`
+}
+
+// MiniAppFeatures lists capabilities of the host client
+type MiniAppFeatures struct {
+	Haptics                   bool `json:"haptics"`
+	CameraAndMicrophoneAccess bool `json:"cameraAndMicrophoneAccess,omitempty"`
+}
+
+// Validate checks the context against the Mini App schema and the server's size limits
+func (c *MiniAppContext) Validate() error {
+	if c.User.FID == 0 {
+		return errors.New("user.fid is required")
+	}
+	if err := c.User.validate("user"); err != nil {
+		return err
+	}
+
+	switch c.Client.PlatformType {
+	case "", "web", "mobile":
+	default:
+		return fmt.Errorf("client.platformType %q is not web or mobile", c.Client.PlatformType)
+	}
+	if insets := c.Client.SafeAreaInsets; insets != nil {
+		for _, inset := range []float64{insets.Top, insets.Bottom, insets.Left, insets.Right} {
+			if inset < 0 || inset > maxInset {
+				return fmt.Errorf("client.safeAreaInsets must be between 0 and %d", maxInset)
+			}
+		}
+	}
+
+	if c.Location != nil {
+		return c.Location.validate()
+	}
+	return nil
+}
+
+func (u *MiniAppUser) validate(field string) error {
+	if len(u.Username) > maxShortField || len(u.DisplayName) > maxShortField {
+		return fmt.Errorf("%s name fields exceed %d bytes", field, maxShortField)
+	}
+	return validateURL(field+".pfpUrl", u.PfpURL)
+}
+
+func (l *MiniAppLocation) validate() error {
+	switch l.Type {
+	case LocationCastEmbed:
+		if err := validateURL("location.embed", l.Embed); err != nil {
+			return err
+		}
+		fallthrough
+	case LocationCastShare:
+		if l.Cast == nil || l.Cast.Hash == "" {
+			return fmt.Errorf("location.cast is required for %s", l.Type)
+		}
+		if len(l.Cast.Hash) > maxShortField || len(l.Cast.ChannelKey) > maxShortField || len(l.Cast.Text) > maxLongField {
+			return errors.New("location.cast fields exceed the size limits")
+		}
+		return l.Cast.Author.validate("location.cast.author")
+	case LocationNotification:
+		if l.Notification == nil || l.Notification.NotificationID == "" {
+			return errors.New("location.notification is required for notification")
+		}
+		n := l.Notification
+		if len(n.NotificationID) > maxShortField || len(n.Title) > maxShortField || len(n.Body) > maxLongField {
+			return errors.New("location.notification fields exceed the size limits")
+		}
+	case LocationChannel:
+		if l.Channel == nil || l.Channel.Key == "" {
+			return errors.New("location.channel is required for channel")
+		}
+		if len(l.Channel.Key) > maxShortField || len(l.Channel.Name) > maxShortField {
+			return errors.New("location.channel fields exceed the size limits")
+		}
+		return validateURL("location.channel.imageUrl", l.Channel.ImageURL)
+	case LocationOpenMiniApp:
+		if len(l.ReferrerDomain) > maxShortField {
+			return errors.New("location.referrerDomain exceeds the size limits")
+		}
+	case LocationLauncher:
+	default:
+		return fmt.Errorf("location.type %q is not supported", l.Type)
+	}
+	return nil
+}
+
+// validateURL accepts an empty value or an absolute http(s) URL
+func validateURL(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > maxLongField {
+		return fmt.Errorf("%s exceeds %d bytes", field, maxLongField)
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%s is not an http(s) URL", field)
+	}
+	return nil
+}
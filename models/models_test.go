@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 )
 
@@ -48,3 +51,68 @@ func TestClickDataEmpty(t *testing.T) {
 		t.Errorf("Expected Count to be 0, got %d", data.Count)
 	}
 }
+
+func TestAppError(t *testing.T) {
+	cause := errors.New("open templates/pages/home.html: no such file")
+	err := NewAppError(http.StatusInternalServerError, "", cause)
+
+	if err.Message != "Internal Server Error" {
+		t.Errorf("Expected default message from status text, got %s", err.Message)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("AppError should unwrap to its cause")
+	}
+	if err.Error() != "Internal Server Error: open templates/pages/home.html: no such file" {
+		t.Errorf("Unexpected error string: %s", err.Error())
+	}
+
+	var appErr *AppError
+	if !errors.As(fmt.Errorf("render: %w", err), &appErr) || appErr.Status != http.StatusInternalServerError {
+		t.Error("AppError should be found through wrapping")
+	}
+}
+
+func TestMiniAppContextValidate(t *testing.T) {
+	valid := func() MiniAppContext {
+		return MiniAppContext{
+			User:   MiniAppUser{FID: 3, Username: "dwr", PfpURL: "https://example.com/pfp.png"},
+			Client: MiniAppClient{PlatformType: "mobile", ClientFID: 9152, SafeAreaInsets: &SafeAreaInsets{Top: 47, Bottom: 34}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *MiniAppContext)
+		wantErr bool
+	}{
+		{"valid", func(c *MiniAppContext) {}, false},
+		{"launcher", func(c *MiniAppContext) { c.Location = &MiniAppLocation{Type: LocationLauncher} }, false},
+		{"cast embed", func(c *MiniAppContext) {
+			c.Location = &MiniAppLocation{Type: LocationCastEmbed, Embed: "https://app.example.com", Cast: &MiniAppCast{Hash: "0xabc", Author: MiniAppUser{FID: 2}}}
+		}, false},
+		{"notification", func(c *MiniAppContext) {
+			c.Location = &MiniAppLocation{Type: LocationNotification, Notification: &MiniAppNotification{NotificationID: "n1", Title: "Hi"}}
+		}, false},
+		{"missing fid", func(c *MiniAppContext) { c.User.FID = 0 }, true},
+		{"unknown platform", func(c *MiniAppContext) { c.Client.PlatformType = "desktop" }, true},
+		{"negative inset", func(c *MiniAppContext) { c.Client.SafeAreaInsets.Left = -1 }, true},
+		{"non-http pfp", func(c *MiniAppContext) { c.User.PfpURL = "javascript:alert(1)" }, true},
+		{"unknown location", func(c *MiniAppContext) { c.Location = &MiniAppLocation{Type: "billboard"} }, true},
+		{"cast embed without cast", func(c *MiniAppContext) {
+			c.Location = &MiniAppLocation{Type: LocationCastEmbed, Embed: "https://app.example.com"}
+		}, true},
+		{"channel without key", func(c *MiniAppContext) {
+			c.Location = &MiniAppLocation{Type: LocationChannel, Channel: &MiniAppChannel{}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid()
+			tt.modify(&c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
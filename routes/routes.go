@@ -1,34 +1,235 @@
 package routes
 
 import (
+	"log/slog"
 	"net/http"
+	"net/http/pprof"
 
 	"github.com/gorilla/mux"
+	"hello-world/config"
 	"hello-world/handlers"
 	"hello-world/middleware"
+	"hello-world/services"
 )
 
+// SetupRoutes builds the router from the environment configuration
 func SetupRoutes() *mux.Router {
+	return NewRouter(config.Load())
+}
+
+// NewRouter builds the router for the given configuration
+func NewRouter(cfg *config.Config) *mux.Router {
+	return newRouter(cfg, middlewareTable{})
+}
+
+func newRouter(cfg *config.Config, mw middlewareTable) *mux.Router {
 	r := mux.NewRouter()
 
-	// Healthcheck endpoint without middleware
-	r.HandleFunc("/health", handlers.HealthcheckHandler).Methods("GET")
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		slog.Warn("Ignoring invalid trusted proxies", "error", err)
+	}
+
+	// Healthcheck and probe endpoints without middleware
+	registerProbes(r)
+
+	// Admin tooling stays on the public port unless it has its own listener
+	if cfg.Admin.Addr == "" {
+		registerProfiling(r, cfg, mw)
+	}
 
 	// Apply unified observability middleware to a subrouter for all other routes
+	observedMiddleware := []mux.MiddlewareFunc{
+		middleware.ObservabilityMiddleware,
+		middleware.ClientIPMiddleware(trustedProxies),
+		middleware.FarcasterContextMiddleware(handlers.LookupFarcasterContext),
+		middleware.SecurityHeadersMiddleware(middleware.SecurityHeadersOptions{
+			ReportOnly:     cfg.Security.CSPReportOnly,
+			FrameAncestors: cfg.Security.FrameAncestors,
+			HSTSMaxAge:     cfg.Security.HSTSMaxAge,
+		}),
+		middleware.AccessLogMiddleware(middleware.AccessLogOptions{
+			Format:        cfg.AccessLog.Format,
+			SampleRate:    cfg.AccessLog.SampleRate,
+			SlowThreshold: cfg.AccessLog.SlowThreshold,
+			Redactor:      middleware.NewRedactor(middleware.RedactorOptions{Keys: cfg.Log.RedactKeys}),
+		}),
+		middleware.CompressionMiddleware(middleware.CompressionOptions{
+			MinSize:   cfg.Server.CompressionMinSize,
+			Encodings: cfg.Server.CompressionEncodings,
+		}),
+		middleware.RecoveryMiddleware(http.HandlerFunc(handlers.ServerErrorHandler)),
+		middleware.TimeoutMiddleware(cfg.Server.RequestTimeout, http.HandlerFunc(handlers.ServiceUnavailableHandler)),
+		middleware.ConditionalGetMiddleware,
+	}
 	observed := r.NewRoute().Subrouter()
-	observed.Use(middleware.ObservabilityMiddleware)
+	mw.use(observed, observedMiddleware...)
+
+	// Unmatched routes bypass Use middleware, so wrap the error handlers explicitly
+	r.NotFoundHandler = chain(http.HandlerFunc(handlers.NotFoundHandler), observedMiddleware)
+	r.MethodNotAllowedHandler = chain(http.HandlerFunc(handlers.MethodNotAllowedHandler), observedMiddleware)
 
 	// Full page routes
 	observed.HandleFunc("/", handlers.HomeHandler).Methods("GET")
-	observed.HandleFunc("/debug", handlers.DebugHandler).Methods("GET")
+	observed.HandleFunc("/debug", handlers.DebugHandler(cfg)).Methods("GET")
+	observed.HandleFunc("/version", handlers.VersionHandler).Methods("GET", "HEAD")
+
+	// The API limit covers /api and /api/v1 together, and the click limit both
+	// click endpoints, so each client gets one quota of each
+	apiLimit := rateLimit("api", cfg.RateLimit.API, cfg.RateLimit.APIBurst, cfg.RateLimit.APIKeys)
+	clickLimit := rateLimit("click", cfg.RateLimit.Click, cfg.RateLimit.ClickBurst, cfg.RateLimit.APIKeys)
+	apiTimeout := middleware.TimeoutMiddleware(cfg.Server.APITimeout, http.HandlerFunc(handlers.ServiceUnavailableHandler))
 
-	// API routes for HTMX fragments
+	// Versioned JSON API, callable cross-origin by mini apps and separate front-ends.
+	// CORS runs first so preflights are answered without using API tokens and
+	// 429s carry CORS headers the browser can read. It is registered before
+	// the fragment routes because a non-matching subrouter resets mux's
+	// method mismatch, turning their 405s into 404s.
+	v1 := observed.PathPrefix("/api/v1").Subrouter()
+	mw.use(v1,
+		middleware.CORSMiddleware(middleware.CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		}),
+		apiLimit,
+		apiTimeout,
+	)
+	v1.HandleFunc("/time", handlers.TimeJSONHandler).Methods("GET", "HEAD")
+	v1.HandleFunc("/clicks", handlers.ClicksJSONHandler).Methods("GET", "HEAD")
+	v1.Handle("/clicks", chain(http.HandlerFunc(handlers.ClicksJSONHandler), []mux.MiddlewareFunc{
+		clickLimit,
+		maxBody(cfg.Server.ClickMaxBodyBytes),
+	})).Methods("POST")
+	// Preflights must match a route for the CORS middleware to run
+	v1.PathPrefix("/").Methods("OPTIONS").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// HTMX fragments
 	api := observed.PathPrefix("/api").Subrouter()
+	mw.use(api, apiLimit, apiTimeout)
 	api.HandleFunc("/time", handlers.TimeFragmentHandler).Methods("GET")
-	api.HandleFunc("/click", handlers.ClickFragmentHandler).Methods("POST")
+	api.Handle("/rum", chain(http.HandlerFunc(handlers.RUMHandler), []mux.MiddlewareFunc{
+		rateLimit("rum", cfg.RateLimit.RUM, cfg.RateLimit.RUMBurst, cfg.RateLimit.APIKeys),
+		maxBody(cfg.Server.MaxBodyBytes),
+	})).Methods("POST")
+	api.HandleFunc("/farcaster-context", handlers.FarcasterContextHandler).Methods("GET")
+	api.Handle("/farcaster-context", chain(http.HandlerFunc(handlers.FarcasterContextHandler), []mux.MiddlewareFunc{
+		maxBody(cfg.Server.MaxBodyBytes),
+	})).Methods("POST")
+	api.Handle("/sdk-ready", chain(http.HandlerFunc(handlers.SDKReadyHandler), []mux.MiddlewareFunc{
+		maxBody(cfg.Server.ClickMaxBodyBytes),
+	})).Methods("POST")
+	api.Handle("/csp-report", chain(http.HandlerFunc(handlers.CSPReportHandler), []mux.MiddlewareFunc{
+		maxBody(cfg.Server.WebhookMaxBodyBytes),
+	})).Methods("POST")
+	api.Handle("/click", chain(http.HandlerFunc(handlers.ClickFragmentHandler), []mux.MiddlewareFunc{
+		clickLimit,
+		maxBody(cfg.Server.ClickMaxBodyBytes),
+	})).Methods("POST")
+
+	// Admin routes for runtime telemetry settings
+	if cfg.Admin.Addr == "" {
+		registerAdmin(observed, cfg, mw)
+	}
+
+	// Embedded, fingerprinted static files
+	observed.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.HandlerFunc(handlers.StaticHandler))).Methods("GET", "HEAD")
 
-	// Static files
-	observed.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static/"))))
+	return r
+}
+
+// NewAdminRouter builds the router served on cfg.Admin.Addr: the probes,
+// profiling and runtime telemetry settings, kept off the public port so they
+// can be firewalled separately. The admin token is still required. Metrics
+// and traces are pushed over OTLP, so there is no scrape endpoint or trace
+// viewer to host; /admin/diagnostics shows the exporters' status instead.
+func NewAdminRouter(cfg *config.Config) *mux.Router {
+	return newAdminRouter(cfg, middlewareTable{})
+}
+
+func newAdminRouter(cfg *config.Config, mw middlewareTable) *mux.Router {
+	r := mux.NewRouter()
+	registerProbes(r)
+	registerProfiling(r, cfg, mw)
 
+	observed := r.NewRoute().Subrouter()
+	mw.use(observed,
+		middleware.ObservabilityMiddleware,
+		middleware.RecoveryMiddleware(http.HandlerFunc(handlers.ServerErrorHandler)),
+	)
+	registerAdmin(observed, cfg, mw)
 	return r
 }
+
+// registerProbes mounts the healthcheck and the liveness, readiness and startup probes
+func registerProbes(r *mux.Router) {
+	r.HandleFunc("/health", handlers.HealthcheckHandler).Methods("GET")
+	r.HandleFunc("/livez", handlers.ProbeHandler(services.ProbeLive)).Methods("GET")
+	r.HandleFunc("/readyz", handlers.ProbeHandler(services.ProbeReady)).Methods("GET")
+	r.HandleFunc("/startupz", handlers.ProbeHandler(services.ProbeStartup)).Methods("GET")
+}
+
+// registerProfiling mounts the admin-only pprof routes. They bypass the
+// observed chain, whose timeout and buffering would cut long CPU profiles and
+// traces short.
+func registerProfiling(r *mux.Router, cfg *config.Config, mw middlewareTable) {
+	profiling := r.PathPrefix("/debug/pprof").Subrouter()
+	mw.use(profiling, middleware.AdminOnly(cfg.Admin.Token))
+	profiling.HandleFunc("/capture", handlers.ProfileCaptureHandler).Methods("GET")
+	profiling.HandleFunc("/cmdline", pprof.Cmdline)
+	profiling.HandleFunc("/profile", pprof.Profile)
+	profiling.HandleFunc("/symbol", pprof.Symbol)
+	profiling.HandleFunc("/trace", pprof.Trace)
+	profiling.PathPrefix("/").HandlerFunc(pprof.Index)
+}
+
+// registerAdmin mounts the admin routes for runtime telemetry settings
+func registerAdmin(r *mux.Router, cfg *config.Config, mw middlewareTable) {
+	admin := r.PathPrefix("/admin").Subrouter()
+	mw.use(admin, middleware.AdminOnly(cfg.Admin.Token), maxBody(cfg.Server.MaxBodyBytes))
+	admin.HandleFunc("/log-level", handlers.LogLevelHandler).Methods("GET", "PUT", "POST")
+	admin.HandleFunc("/sampler", handlers.SamplerHandler).Methods("GET", "PUT", "POST")
+	admin.HandleFunc("/diagnostics", handlers.DiagnosticsHandler(cfg)).Methods("GET")
+}
+
+// rateLimit returns a per-client token-bucket middleware, or a pass-through
+// when rate is zero. Clients are told apart by a configured API key or their IP.
+func rateLimit(name string, rate float64, burst int, apiKeys []string) mux.MiddlewareFunc {
+	if rate <= 0 {
+		return passThrough
+	}
+	return middleware.NewRateLimiter(middleware.RateLimitOptions{
+		Name:  name,
+		Rate:  rate,
+		Burst: burst,
+		Key:   middleware.APIKeyRateLimitKey(apiKeys),
+	}).Middleware
+}
+
+// maxBody limits request bodies of a route. Nested limits collapse to the
+// smallest, so each route or subrouter is given exactly one.
+func maxBody(limit int64) mux.MiddlewareFunc {
+	return middleware.MaxBodyMiddleware(limit, http.HandlerFunc(handlers.RequestTooLargeHandler))
+}
+
+// passThrough is the middleware of disabled features
+func passThrough(next http.Handler) http.Handler {
+	return next
+}
+
+// chained is a handler wrapped by chain; it keeps the middleware for the route table
+type chained struct {
+	http.Handler
+	middleware []mux.MiddlewareFunc
+}
+
+// chain wraps h with middleware so that the first entry runs outermost, matching mux's Use order
+func chain(h http.Handler, middleware []mux.MiddlewareFunc) http.Handler {
+	wrapped := h
+	for i := len(middleware) - 1; i >= 0; i-- {
+		wrapped = middleware[i](wrapped)
+	}
+	return chained{Handler: wrapped, middleware: middleware}
+}
@@ -1,10 +1,14 @@
 package routes
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"hello-world/config"
 )
 
 func TestSetupRoutes(t *testing.T) {
@@ -202,3 +206,331 @@ func TestHealthcheckRouteMethodNotAllowed(t *testing.T) {
 		t.Errorf("POST to healthcheck route should return MethodNotAllowed, got %v", status)
 	}
 }
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, Admin: config.AdminConfig{Token: "secret"}})
+
+	tests := []struct {
+		auth           string
+		expectedStatus int
+	}{
+		{"", http.StatusForbidden},
+		{"Bearer wrong", http.StatusForbidden},
+		{"Bearer secret", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/admin/log-level", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("Authorization %q: expected status %d, got %d", test.auth, test.expectedStatus, rr.Code)
+		}
+	}
+}
+
+func TestAdminRoutesDisabledWithoutToken(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}})
+
+	req := httptest.NewRequest("GET", "/admin/sampler", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Admin routes should be closed without a configured token, got %d", rr.Code)
+	}
+}
+
+func TestClickRouteRateLimited(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, RateLimit: config.RateLimitConfig{Click: 0.001, ClickBurst: 2}})
+
+	codes := make([]int, 3)
+	for i := range codes {
+		req := httptest.NewRequest("POST", "/api/click", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		codes[i] = rr.Code
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected 200, 200, 429 from click route, got %v", codes)
+	}
+}
+
+func TestClickRouteIgnoresUnknownAPIKeys(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, RateLimit: config.RateLimitConfig{Click: 0.001, ClickBurst: 2, APIKeys: []string{"known"}}})
+
+	send := func(apiKey string) int {
+		req := httptest.NewRequest("POST", "/api/click", nil)
+		req.Header.Set("X-Api-Key", apiKey)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	limited := 0
+	for i := range 10 {
+		if send(fmt.Sprintf("random-%d", i)) == http.StatusTooManyRequests {
+			limited++
+		}
+	}
+	if limited != 8 {
+		t.Errorf("Expected invented API keys to share the IP bucket, %d of 10 limited", limited)
+	}
+	if code := send("known"); code != http.StatusOK {
+		t.Errorf("Expected a configured API key to get its own bucket, got %d", code)
+	}
+}
+
+func TestNotFoundRouteRendersErrorPage(t *testing.T) {
+	router := SetupRoutes()
+
+	req := httptest.NewRequest("GET", "/nonexistent", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rr.Code)
+	}
+	// The observability middleware now wraps unmatched routes
+	if rr.Header().Get("X-Request-Id") == "" {
+		t.Error("404 responses should carry a request ID")
+	}
+	if strings.Contains(rr.Body.String(), "404 page not found") {
+		t.Error("404 should not use the default mux response")
+	}
+}
+
+func TestMethodNotAllowedHTMXFragment(t *testing.T) {
+	router := SetupRoutes()
+
+	req := httptest.NewRequest("GET", "/api/click", nil)
+	req.Header.Set("HX-Request", "true")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rr.Code)
+	}
+	if rr.Header().Get("X-Request-Id") == "" {
+		t.Error("405 responses should carry a request ID")
+	}
+}
+
+func TestClickRouteBodyLimit(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080", ClickMaxBodyBytes: 16, WebhookMaxBodyBytes: 1 << 20}})
+
+	req := httptest.NewRequest("POST", "/api/click", strings.NewReader(strings.Repeat("x", 64)))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+
+	// The CSP report receiver has its own, larger limit
+	report := `{"csp-report":{"violated-directive":"script-src","blocked-uri":"` + strings.Repeat("x", 64) + `"}}`
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/csp-report", strings.NewReader(report)))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 for CSP report, got %d", rr.Code)
+	}
+}
+
+func TestAPIv1CORS(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, CORS: config.CORSConfig{AllowedOrigins: []string{"https://*.farcaster.xyz"}, MaxAge: time.Minute}})
+
+	req := httptest.NewRequest("OPTIONS", "/api/v1/clicks", nil)
+	req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent || rr.Header().Get("Access-Control-Allow-Origin") != "https://miniapp.farcaster.xyz" {
+		t.Errorf("Expected successful preflight, got %d %v", rr.Code, rr.Header())
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/clicks", nil)
+	req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"count"`) {
+		t.Errorf("Expected JSON click count, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("HX-Trigger") != "clicksChanged" || !strings.Contains(rr.Header().Get("Access-Control-Expose-Headers"), "HX-Trigger") {
+		t.Errorf("Expected exposed HX-Trigger, got %v", rr.Header())
+	}
+
+	// Fragment routes do not get CORS headers
+	req = httptest.NewRequest("GET", "/api/time", nil)
+	req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("CORS should only apply to /api/v1")
+	}
+}
+
+func TestAPIv1RateLimitAfterCORS(t *testing.T) {
+	router := NewRouter(&config.Config{
+		Server:    config.ServerConfig{Port: "8080"},
+		RateLimit: config.RateLimitConfig{API: 0.001, APIBurst: 5, Click: 0.001, ClickBurst: 2},
+		CORS:      config.CORSConfig{AllowedOrigins: []string{"https://*.farcaster.xyz"}},
+	})
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", "https://miniapp.farcaster.xyz")
+		if method == "OPTIONS" {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Preflights do not use API tokens
+	for range 30 {
+		if rr := send("OPTIONS", "/api/v1/clicks"); rr.Code != http.StatusNoContent {
+			t.Fatalf("Expected preflights not to be rate limited, got %d", rr.Code)
+		}
+	}
+
+	// Both click endpoints share one click quota
+	codes := []int{send("POST", "/api/v1/clicks").Code, send("POST", "/api/click").Code}
+	rr := send("POST", "/api/v1/clicks")
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 200, 200, 429 across the click endpoints, got %v %d", codes, rr.Code)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://miniapp.farcaster.xyz" {
+		t.Errorf("Expected the 429 to carry CORS headers, got %v", rr.Header())
+	}
+}
+
+func TestProfilingRoutesRequireAdmin(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, Admin: config.AdminConfig{Token: "secret"}})
+
+	tests := []struct {
+		path           string
+		auth           string
+		expectedStatus int
+	}{
+		{"/debug/pprof/", "", http.StatusForbidden},
+		{"/debug/pprof/goroutine?debug=1", "", http.StatusForbidden},
+		{"/debug/pprof/capture", "Bearer wrong", http.StatusForbidden},
+		{"/debug/pprof/", "Bearer secret", http.StatusOK},
+		{"/debug/pprof/goroutine?debug=1", "Bearer secret", http.StatusOK},
+		{"/debug/pprof/capture?seconds=0", "Bearer secret", http.StatusBadRequest},
+		{"/debug/pprof/capture?kinds=heap,memory", "Bearer secret", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != test.expectedStatus {
+			t.Errorf("%s with %q: expected status %d, got %d", test.path, test.auth, test.expectedStatus, rr.Code)
+		}
+	}
+}
+
+func TestProfileCaptureArchive(t *testing.T) {
+	router := NewRouter(&config.Config{Server: config.ServerConfig{Port: "8080"}, Admin: config.AdminConfig{Token: "secret"}})
+
+	req := httptest.NewRequest("GET", "/debug/pprof/capture?kinds=heap,goroutine", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Type") != "application/gzip" {
+		t.Errorf("Expected a gzip archive, got %q", rr.Header().Get("Content-Type"))
+	}
+	if disposition := rr.Header().Get("Content-Disposition"); !strings.Contains(disposition, "attachment") || !strings.Contains(disposition, config.CommitHash) {
+		t.Errorf("Expected a download named after the commit, got %q", disposition)
+	}
+}
+
+func TestAdminListener(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{Port: "8080"}, Admin: config.AdminConfig{Token: "secret", Addr: "127.0.0.1:9090"}}
+	public, admin := NewRouter(cfg), NewAdminRouter(cfg)
+
+	tests := []struct {
+		path         string
+		publicStatus int
+		adminStatus  int
+	}{
+		{"/health", http.StatusOK, http.StatusOK},
+		{"/admin/log-level", http.StatusNotFound, http.StatusOK},
+		{"/admin/sampler", http.StatusNotFound, http.StatusOK},
+		{"/debug/pprof/", http.StatusNotFound, http.StatusOK},
+		{"/api/v1/time", http.StatusOK, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		for _, target := range []struct {
+			name     string
+			router   http.Handler
+			expected int
+		}{
+			{"public", public, test.publicStatus},
+			{"admin", admin, test.adminStatus},
+		} {
+			req := httptest.NewRequest("GET", test.path, nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rr := httptest.NewRecorder()
+			target.router.ServeHTTP(rr, req)
+
+			if rr.Code != target.expected {
+				t.Errorf("%s %s: expected status %d, got %d", target.name, test.path, target.expected, rr.Code)
+			}
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/admin/log-level", nil)
+	rr := httptest.NewRecorder()
+	admin.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("The admin listener should still require the token, got %d", rr.Code)
+	}
+}
+
+func TestRouteTable(t *testing.T) {
+	table, err := Table(&config.Config{Server: config.ServerConfig{Port: "8080"}, RateLimit: config.RateLimitConfig{Click: 1}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	routes := map[string]RouteInfo{}
+	for _, route := range table {
+		routes[strings.Join(route.Methods, ",")+" "+route.Path] = route
+	}
+	if _, ok := routes[" /api"]; ok {
+		t.Error("Subrouter prefixes should not be listed as routes")
+	}
+	if health := routes["GET /health"]; len(health.Middleware) != 0 {
+		t.Errorf("The healthcheck should have no middleware, got %v", health.Middleware)
+	}
+
+	click, ok := routes["POST /api/click"]
+	if !ok {
+		t.Fatalf("Expected POST /api/click in %v", table)
+	}
+	middleware := strings.Join(click.Middleware, " ")
+	for _, name := range []string{"middleware.ObservabilityMiddleware", "middleware.TimeoutMiddleware", "middleware.(*RateLimiter).Middleware", "middleware.MaxBodyMiddleware"} {
+		if !strings.Contains(middleware, name) {
+			t.Errorf("Expected %s on /api/click, got %v", name, click.Middleware)
+		}
+	}
+	if !strings.HasPrefix(middleware, "middleware.ObservabilityMiddleware") || !strings.HasSuffix(middleware, "middleware.MaxBodyMiddleware") {
+		t.Errorf("Middleware should be listed outermost first, got %v", click.Middleware)
+	}
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"hello-world/config"
+)
+
+// middlewareTable records the middleware added to each router, which mux
+// does not expose, so the route table can list them
+type middlewareTable map[*mux.Router][]mux.MiddlewareFunc
+
+// use adds middleware to r and records them
+func (t middlewareTable) use(r *mux.Router, middleware ...mux.MiddlewareFunc) {
+	r.Use(middleware...)
+	t[r] = append(t[r], middleware...)
+}
+
+// RouteInfo is one row of the route table
+type RouteInfo struct {
+	Path    string
+	Methods []string
+	// Middleware lists the router and route middleware, outermost first
+	Middleware []string
+}
+
+// Table lists the routes of the public router, or of the admin router when
+// admin is set, with their methods and middleware
+func Table(cfg *config.Config, admin bool) ([]RouteInfo, error) {
+	mw := middlewareTable{}
+	var r *mux.Router
+	if admin {
+		r = newAdminRouter(cfg, mw)
+	} else {
+		r = newRouter(cfg, mw)
+	}
+
+	// Walk passes the router holding each route, so the subrouter of every
+	// ancestor is known by the time its descendants are visited
+	subrouters := map[*mux.Route]*mux.Router{}
+	type row struct {
+		route *mux.Route
+		info  RouteInfo
+	}
+	var rows []row
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if len(ancestors) > 0 {
+			subrouters[ancestors[len(ancestors)-1]] = router
+		}
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			// Subrouters without a path of their own, such as the observed chain
+			return nil
+		}
+
+		info := RouteInfo{Path: path}
+		info.Methods, _ = route.GetMethods()
+		for _, ancestor := range ancestors {
+			info.Middleware = append(info.Middleware, middlewareNames(mw[subrouters[ancestor]])...)
+		}
+		if c, ok := route.GetHandler().(chained); ok {
+			info.Middleware = append(info.Middleware, middlewareNames(c.middleware)...)
+		}
+		rows = append(rows, row{route, info})
+		return nil
+	})
+
+	// Path prefixes of subrouters are not routes themselves
+	table := make([]RouteInfo, 0, len(rows))
+	for _, row := range rows {
+		if _, ok := subrouters[row.route]; !ok {
+			table = append(table, row.info)
+		}
+	}
+	return table, err
+}
+
+// closureSuffix matches the compiler-generated suffix of closures and method values
+var closureSuffix = regexp.MustCompile(`(\.func\d+)+$|-fm$`)
+
+// middlewareNames names middleware after the function that created them,
+// e.g. "middleware.AdminOnly" or "middleware.(*RateLimiter).Middleware"
+func middlewareNames(middleware []mux.MiddlewareFunc) []string {
+	names := make([]string, 0, len(middleware))
+	for _, m := range middleware {
+		name := runtime.FuncForPC(reflect.ValueOf(m).Pointer()).Name()
+		name = closureSuffix.ReplaceAllString(name, "")
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		if name == "routes.passThrough" {
+			// Disabled features, such as rate limits set to zero
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
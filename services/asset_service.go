@@ -0,0 +1,115 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// assetHashLength is the number of hex characters of the content hash used in fingerprinted names
+const assetHashLength = 12
+
+// Precompressed variants are stored next to an asset with these suffixes
+var precompressedSuffixes = map[string]string{
+	"br":   ".br",
+	"gzip": ".gz",
+}
+
+// Asset is a static file with its fingerprint and precompressed variants
+type Asset struct {
+	// Name is the path relative to the static root, e.g. "css/style.css"
+	Name string
+	// HashedName inserts the content hash before the extension, e.g. "css/style.3f2a9c0d1b4e.css"
+	HashedName string
+	// Hash is the truncated hex SHA-256 of the content
+	Hash string
+	// Content is the uncompressed file
+	Content []byte
+	// Variants maps a content coding ("br", "gzip") to its precompressed content
+	Variants map[string][]byte
+}
+
+// AssetService fingerprints a tree of static files and resolves both plain
+// and hashed names to their content
+type AssetService struct {
+	assets map[string]*Asset
+	hashed map[string]*Asset
+}
+
+// NewAssetService reads and hashes every file in fsys. Go sources and
+// precompressed variants are not assets themselves; variants are attached to
+// the file they were generated from.
+func NewAssetService(fsys fs.FS) (*AssetService, error) {
+	s := &AssetService{assets: make(map[string]*Asset), hashed: make(map[string]*Asset)}
+	variants := make(map[string]map[string][]byte)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) == ".go" {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		for encoding, suffix := range precompressedSuffixes {
+			if base, ok := strings.CutSuffix(name, suffix); ok {
+				if variants[base] == nil {
+					variants[base] = make(map[string][]byte)
+				}
+				variants[base][encoding] = content
+				return nil
+			}
+		}
+
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])[:assetHashLength]
+		ext := path.Ext(name)
+		asset := &Asset{
+			Name:       name,
+			HashedName: strings.TrimSuffix(name, ext) + "." + hash + ext,
+			Hash:       hash,
+			Content:    content,
+		}
+		s.assets[asset.Name] = asset
+		s.hashed[asset.HashedName] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for base, byEncoding := range variants {
+		if asset, ok := s.assets[base]; ok {
+			asset.Variants = byEncoding
+		}
+	}
+	return s, nil
+}
+
+// URL returns the fingerprinted URL of name under /static/, or the plain
+// URL when name is not a known asset
+func (s *AssetService) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if asset, ok := s.assets[name]; ok {
+		return "/static/" + asset.HashedName
+	}
+	return "/static/" + name
+}
+
+// Lookup resolves a path relative to the static root. hashed reports whether
+// the path carried the content hash, which makes the response immutable.
+func (s *AssetService) Lookup(name string) (asset *Asset, hashed bool, ok bool) {
+	if asset, ok := s.hashed[name]; ok {
+		return asset, true, true
+	}
+	asset, ok = s.assets[name]
+	return asset, false, ok
+}
+
+// Len returns the number of assets
+func (s *AssetService) Len() int {
+	return len(s.assets)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func testAssets(t *testing.T) *AssetService {
+	t.Helper()
+	s, err := NewAssetService(fstest.MapFS{
+		"static.go":        {Data: []byte("package static")},
+		"css/style.css":    {Data: []byte("body { color: red; }")},
+		"css/style.css.gz": {Data: []byte("gzip")},
+		"css/style.css.br": {Data: []byte("brotli")},
+		"js/main.js":       {Data: []byte("console.log('hi')")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestNewAssetService(t *testing.T) {
+	s := testAssets(t)
+
+	if s.Len() != 2 {
+		t.Fatalf("Expected 2 assets (sources and variants excluded), got %d", s.Len())
+	}
+
+	asset, hashed, ok := s.Lookup("css/style.css")
+	if !ok || hashed {
+		t.Fatalf("Expected plain lookup of css/style.css, got ok=%v hashed=%v", ok, hashed)
+	}
+	if len(asset.Hash) != assetHashLength {
+		t.Errorf("Expected %d character hash, got %q", assetHashLength, asset.Hash)
+	}
+	if asset.HashedName != "css/style."+asset.Hash+".css" {
+		t.Errorf("Unexpected hashed name %q", asset.HashedName)
+	}
+	if string(asset.Variants["gzip"]) != "gzip" || string(asset.Variants["br"]) != "brotli" {
+		t.Errorf("Expected precompressed variants, got %v", asset.Variants)
+	}
+
+	if byHash, hashed, ok := s.Lookup(asset.HashedName); !ok || !hashed || byHash != asset {
+		t.Error("Hashed name should resolve to the same asset")
+	}
+	if _, _, ok := s.Lookup("css"); ok {
+		t.Error("Directories should not resolve")
+	}
+	if _, _, ok := s.Lookup("static.go"); ok {
+		t.Error("Go sources should not be served")
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	s := testAssets(t)
+	asset, _, _ := s.Lookup("js/main.js")
+
+	if url := s.URL("js/main.js"); url != "/static/"+asset.HashedName {
+		t.Errorf("Expected fingerprinted URL, got %q", url)
+	}
+	if url := s.URL("/js/main.js"); url != "/static/"+asset.HashedName {
+		t.Errorf("Leading slash should be ignored, got %q", url)
+	}
+	if url := s.URL("img/missing.png"); url != "/static/img/missing.png" {
+		t.Errorf("Unknown assets should keep their plain URL, got %q", url)
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// unknownBuildValue is the default of build values not stamped with -ldflags
+const unknownBuildValue = "unknown"
+
+// BuildInfo describes the running binary for /version, the page footer and the build_info metric
+type BuildInfo struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	// Dirty reports uncommitted changes in the build's VCS checkout
+	Dirty        bool         `json:"dirty"`
+	Module       string       `json:"module,omitempty"`
+	Version      string       `json:"version,omitempty"`
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// Dependency is a module compiled into the binary
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Replace is the replacement module, e.g. "../fork v1.2.3"
+	Replace string `json:"replace,omitempty"`
+}
+
+// ReadBuildInfo combines the -ldflags values with the module and VCS
+// information embedded by the Go toolchain, which fills in the commit and
+// build time when the flags were not set
+func ReadBuildInfo(commit, buildTime string) BuildInfo {
+	info, _ := debug.ReadBuildInfo()
+	return buildInfoFrom(info, commit, buildTime)
+}
+
+func buildInfoFrom(info *debug.BuildInfo, commit, buildTime string) BuildInfo {
+	b := BuildInfo{Commit: commit, BuildTime: buildTime, GoVersion: runtime.Version(), Dependencies: []Dependency{}}
+	if info == nil {
+		return b
+	}
+	b.Module, b.Version = info.Main.Path, info.Main.Version
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if b.Commit == "" || b.Commit == unknownBuildValue {
+				b.Commit = setting.Value
+			}
+		case "vcs.time":
+			if b.BuildTime == "" || b.BuildTime == unknownBuildValue {
+				b.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			b.Dirty, _ = strconv.ParseBool(setting.Value)
+		}
+	}
+	for _, dep := range info.Deps {
+		d := Dependency{Path: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			d.Replace = dep.Replace.Path + " " + dep.Replace.Version
+		}
+		b.Dependencies = append(b.Dependencies, d)
+	}
+	return b
+}
+
+// RegisterBuildInfoMetric registers the build_info gauge, which is always 1
+// and labelled with the build so deployments can be tracked over time.
+// Dependencies are left out to keep the series small; see /version.
+func RegisterBuildInfoMetric(b BuildInfo) {
+	attrs := metric.WithAttributes(
+		attribute.String("commit", b.Commit),
+		attribute.String("build_time", b.BuildTime),
+		attribute.String("go_version", b.GoVersion),
+		attribute.Bool("dirty", b.Dirty),
+		attribute.String("module", b.Module),
+		attribute.String("module_version", b.Version),
+	)
+	_, err := businessMeter.Int64ObservableGauge(
+		"build_info",
+		metric.WithDescription("Build of the running binary; always 1"),
+		metric.WithUnit("1"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, attrs)
+			return nil
+		}),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize build info metric", "error", err)
+	}
+}
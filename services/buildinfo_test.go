@@ -0,0 +1,42 @@
+package services
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+func TestBuildInfoFrom(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "hello-world", Version: "(devel)"},
+		Deps: []*debug.Module{
+			{Path: "github.com/gorilla/mux", Version: "v1.8.1"},
+			{Path: "example.com/forked", Version: "v1.0.0", Replace: &debug.Module{Path: "../forked", Version: "v1.0.1"}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2025-08-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	b := buildInfoFrom(info, "unknown", "unknown")
+	if b.Commit != "0123abcd" || b.BuildTime != "2025-08-01T12:00:00Z" || !b.Dirty {
+		t.Errorf("Expected VCS values to fill in the unset flags, got %+v", b)
+	}
+	if b.GoVersion != runtime.Version() || b.Module != "hello-world" {
+		t.Errorf("Unexpected module or Go version: %+v", b)
+	}
+	if len(b.Dependencies) != 2 || b.Dependencies[1].Replace != "../forked v1.0.1" {
+		t.Errorf("Unexpected dependencies: %+v", b.Dependencies)
+	}
+
+	b = buildInfoFrom(info, "fedcba98", "2025-08-02T08:00:00Z")
+	if b.Commit != "fedcba98" || b.BuildTime != "2025-08-02T08:00:00Z" {
+		t.Errorf("The -ldflags values should take precedence, got %+v", b)
+	}
+
+	if b := buildInfoFrom(nil, "unknown", "unknown"); b.Commit != "unknown" || b.Dependencies == nil {
+		t.Errorf("Expected defaults without build info, got %+v", b)
+	}
+}
@@ -1,22 +1,38 @@
 package services
 
-var clickCount = 0
+import (
+	"context"
+	"sync/atomic"
+)
+
+// DefaultClickCounter names the single click counter in metrics
+const DefaultClickCounter = "default"
+
+var clickCount atomic.Int64
 
 type ClickService struct{}
 
 func NewClickService() *ClickService {
+	// Registers the clicks.current gauge
+	businessMetricsOnce.Do(initBusinessMetrics)
 	return &ClickService{}
 }
 
 func (s *ClickService) IncrementClick() int {
-	clickCount++
-	return clickCount
+	return int(clickCount.Add(1))
+}
+
+// Click increments the counter and records it on the clicks.total metric
+func (s *ClickService) Click(ctx context.Context, attrs ClickAttributes) int {
+	count := s.IncrementClick()
+	RecordClick(ctx, attrs)
+	return count
 }
 
 func (s *ClickService) GetCount() int {
-	return clickCount
+	return int(clickCount.Load())
 }
 
 func (s *ClickService) Reset() {
-	clickCount = 0
+	clickCount.Store(0)
 }
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// Diagnostics is a snapshot of the process for the admin diagnostics panel
+type Diagnostics struct {
+	Build   BuildDiagnostics
+	Runtime RuntimeDiagnostics
+	Memory  MemoryDiagnostics
+	GC      GCDiagnostics
+}
+
+// BuildDiagnostics describes the running binary
+type BuildDiagnostics struct {
+	Commit    string
+	GoVersion string
+	// Module is the main module path and Settings the -ldflags, VCS and toolchain settings
+	Module   string
+	Settings []debug.BuildSetting
+	Deps     []*debug.Module
+}
+
+// RuntimeDiagnostics describes the scheduler
+type RuntimeDiagnostics struct {
+	Uptime     time.Duration
+	Goroutines int
+	NumCPU     int
+	GOMAXPROCS int
+}
+
+// MemoryDiagnostics are the headline runtime.MemStats, in bytes
+type MemoryDiagnostics struct {
+	HeapAlloc   uint64
+	HeapInuse   uint64
+	HeapObjects uint64
+	StackInuse  uint64
+	Sys         uint64
+	TotalAlloc  uint64
+}
+
+// GCDiagnostics summarizes garbage collection since startup
+type GCDiagnostics struct {
+	NumGC      uint32
+	LastGC     time.Time
+	PauseTotal time.Duration
+	// LastPause is the most recent stop-the-world pause
+	LastPause   time.Duration
+	CPUFraction float64
+	// GOGC is the GC target percentage; zero when GC is disabled
+	GOGC int
+}
+
+// CollectDiagnostics gathers build, runtime, memory and GC information. It
+// calls runtime.ReadMemStats, which briefly stops the world, so it is meant
+// for occasional admin use rather than hot paths.
+func CollectDiagnostics(commit string, startedAt time.Time) Diagnostics {
+	d := Diagnostics{
+		Build: BuildDiagnostics{Commit: commit, GoVersion: runtime.Version()},
+		Runtime: RuntimeDiagnostics{
+			Uptime:     time.Since(startedAt),
+			Goroutines: runtime.NumGoroutine(),
+			NumCPU:     runtime.NumCPU(),
+			GOMAXPROCS: runtime.GOMAXPROCS(0),
+		},
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		d.Build.Module = info.Main.Path
+		d.Build.Settings = info.Settings
+		d.Build.Deps = info.Deps
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	d.Memory = MemoryDiagnostics{
+		HeapAlloc:   mem.HeapAlloc,
+		HeapInuse:   mem.HeapInuse,
+		HeapObjects: mem.HeapObjects,
+		StackInuse:  mem.StackInuse,
+		Sys:         mem.Sys,
+		TotalAlloc:  mem.TotalAlloc,
+	}
+	d.GC = GCDiagnostics{
+		NumGC:       mem.NumGC,
+		PauseTotal:  time.Duration(mem.PauseTotalNs),
+		CPUFraction: mem.GCCPUFraction,
+		GOGC:        currentGOGC(),
+	}
+	if mem.NumGC > 0 {
+		d.GC.LastGC = time.Unix(0, int64(mem.LastGC))
+		d.GC.LastPause = time.Duration(mem.PauseNs[(mem.NumGC+255)%256])
+	}
+	return d
+}
+
+// currentGOGC reads the GC percentage without changing it
+func currentGOGC() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return int(sample[0].Value.Uint64())
+}
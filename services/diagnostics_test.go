@@ -0,0 +1,21 @@
+package services
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCollectDiagnostics(t *testing.T) {
+	d := CollectDiagnostics("abc123", time.Now().Add(-time.Minute))
+
+	if d.Build.Commit != "abc123" || d.Build.GoVersion != runtime.Version() {
+		t.Errorf("Unexpected build info: %+v", d.Build)
+	}
+	if d.Runtime.Uptime < time.Minute || d.Runtime.Goroutines == 0 || d.Runtime.GOMAXPROCS == 0 {
+		t.Errorf("Unexpected runtime info: %+v", d.Runtime)
+	}
+	if d.Memory.HeapAlloc == 0 || d.Memory.Sys == 0 {
+		t.Errorf("Expected memory stats, got %+v", d.Memory)
+	}
+}
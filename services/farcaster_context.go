@@ -0,0 +1,102 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"hello-world/models"
+)
+
+// DefaultSessionTTL is how long a captured Mini App context is kept without being refreshed
+const DefaultSessionTTL = 24 * time.Hour
+
+// maxFarcasterSessions bounds the store so unauthenticated clients cannot grow it without limit
+const maxFarcasterSessions = 10000
+
+type farcasterContextEntry struct {
+	sessionID string
+	context   *models.MiniAppContext
+	expires   time.Time
+}
+
+// FarcasterContextStore keeps the Mini App context reported by each session
+// in memory. Sessions are listed from the most to the least recently updated,
+// which with a single TTL is also expiry order, so sweeping and evicting only
+// touch the back of the list.
+type FarcasterContextStore struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxSessions int
+	entries     map[string]*list.Element
+	order       *list.List
+	now         func() time.Time
+}
+
+// NewFarcasterContextStore creates a store whose entries expire ttl after their last update
+func NewFarcasterContextStore(ttl time.Duration) *FarcasterContextStore {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+	return &FarcasterContextStore{
+		ttl:         ttl,
+		maxSessions: maxFarcasterSessions,
+		entries:     make(map[string]*list.Element),
+		order:       list.New(),
+		now:         time.Now,
+	}
+}
+
+// Put stores the context of a session, replacing any previous one
+func (s *FarcasterContextStore) Put(sessionID string, c *models.MiniAppContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+	entry := farcasterContextEntry{sessionID: sessionID, context: c, expires: now.Add(s.ttl)}
+	if elem, ok := s.entries[sessionID]; ok {
+		elem.Value = entry
+		s.order.MoveToFront(elem)
+		return
+	}
+	if len(s.entries) >= s.maxSessions {
+		s.remove(s.order.Back())
+	}
+	s.entries[sessionID] = s.order.PushFront(entry)
+}
+
+// Get returns the unexpired context of a session
+func (s *FarcasterContextStore) Get(sessionID string) (*models.MiniAppContext, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.entries[sessionID]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(farcasterContextEntry)
+	if s.now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.context, true
+}
+
+// Len returns the number of stored sessions, including expired ones not yet swept
+func (s *FarcasterContextStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// sweep removes expired sessions from the back of the list
+func (s *FarcasterContextStore) sweep(now time.Time) {
+	for elem := s.order.Back(); elem != nil && now.After(elem.Value.(farcasterContextEntry).expires); elem = s.order.Back() {
+		s.remove(elem)
+	}
+}
+
+func (s *FarcasterContextStore) remove(elem *list.Element) {
+	delete(s.entries, elem.Value.(farcasterContextEntry).sessionID)
+	s.order.Remove(elem)
+}
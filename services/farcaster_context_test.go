@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"hello-world/models"
+)
+
+func TestFarcasterContextStore(t *testing.T) {
+	store := NewFarcasterContextStore(time.Hour)
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Put("session-a", &models.MiniAppContext{User: models.MiniAppUser{FID: 3}})
+	if fc, ok := store.Get("session-a"); !ok || fc.User.FID != 3 {
+		t.Fatalf("Expected stored context, got %v %v", fc, ok)
+	}
+	if _, ok := store.Get("session-b"); ok {
+		t.Error("Unknown sessions should have no context")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := store.Get("session-a"); ok {
+		t.Error("Expired contexts should not be returned")
+	}
+	store.Put("session-b", &models.MiniAppContext{User: models.MiniAppUser{FID: 4}})
+	if store.Len() != 1 {
+		t.Errorf("Expected the expired session to be swept, got %d sessions", store.Len())
+	}
+}
+
+func TestFarcasterContextStoreEvictsLeastRecentlyUpdated(t *testing.T) {
+	store := NewFarcasterContextStore(time.Hour)
+	store.maxSessions = 2
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Put("session-a", &models.MiniAppContext{User: models.MiniAppUser{FID: 3}})
+	now = now.Add(time.Minute)
+	store.Put("session-b", &models.MiniAppContext{User: models.MiniAppUser{FID: 4}})
+	now = now.Add(time.Minute)
+	// Updating session-a makes session-b the oldest
+	store.Put("session-a", &models.MiniAppContext{User: models.MiniAppUser{FID: 5}})
+	store.Put("session-c", &models.MiniAppContext{User: models.MiniAppUser{FID: 6}})
+
+	if store.Len() != 2 {
+		t.Fatalf("Expected the store to stay at its bound, got %d sessions", store.Len())
+	}
+	if _, ok := store.Get("session-b"); ok {
+		t.Error("Expected the least recently updated session to be evicted")
+	}
+	if fc, ok := store.Get("session-a"); !ok || fc.User.FID != 5 {
+		t.Errorf("Expected the updated session to be kept, got %v %v", fc, ok)
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Probes answered by HealthRegistry.Probe
+const (
+	// ProbeLive reports whether the process is running; it runs no checks, so
+	// a broken dependency never gets the process restarted
+	ProbeLive = "livez"
+	// ProbeReady reports whether the process should receive traffic
+	ProbeReady = "readyz"
+	// ProbeStartup reports whether startup has finished
+	ProbeStartup = "startupz"
+)
+
+// Health statuses of a report and its checks
+const (
+	HealthOK = "ok"
+	// HealthDegraded means only non-critical checks failed; the probe still passes
+	HealthDegraded = "degraded"
+	HealthFail     = "fail"
+)
+
+// Defaults of HealthRegistry
+const (
+	DefaultHealthCheckTimeout = 2 * time.Second
+	DefaultHealthCacheTTL     = 2 * time.Second
+)
+
+// Attribute keys of the health metrics
+const (
+	healthCheckKey  = attribute.Key("health.check")
+	healthStatusKey = attribute.Key("health.status")
+)
+
+var (
+	healthCheckDuration metric.Float64Histogram
+	healthMetricsOnce   sync.Once
+)
+
+// HealthCheck is a named dependency check run by the readiness and startup probes
+type HealthCheck struct {
+	Name string
+	// Check returns nil when the dependency is healthy; it should honour ctx
+	Check func(ctx context.Context) error
+	// Timeout bounds one run; defaults to DefaultHealthCheckTimeout
+	Timeout time.Duration
+	// Critical checks fail the probe; others only mark it degraded
+	Critical bool
+}
+
+// HealthCheckResult is the outcome of one check in a HealthReport
+type HealthCheckResult struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Critical   bool      `json:"critical"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+	// Cached reports that the result was reused from a recent run
+	Cached bool `json:"cached"`
+}
+
+// HealthReport is the JSON body of a probe
+type HealthReport struct {
+	Probe  string `json:"probe"`
+	Status string `json:"status"`
+	// Reason explains a failure that is not caused by a check, e.g. "draining"
+	Reason string              `json:"reason,omitempty"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// registeredCheck caches the last result of a check. mu is held while the
+// check runs, so concurrent probes wait for one run instead of starting more.
+type registeredCheck struct {
+	HealthCheck
+	mu   sync.Mutex
+	last HealthCheckResult
+}
+
+// HealthRegistry runs registered checks for the liveness, readiness and
+// startup probes. Results are cached for a short time so that frequent probes
+// from several sources do not hammer the dependencies.
+type HealthRegistry struct {
+	cacheTTL time.Duration
+	started  atomic.Bool
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []*registeredCheck
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewHealthRegistry creates a registry whose results are reused for cacheTTL
+func NewHealthRegistry(cacheTTL time.Duration) *HealthRegistry {
+	return &HealthRegistry{cacheTTL: cacheTTL, now: time.Now}
+}
+
+// Register adds a check, replacing any check with the same name
+func (h *HealthRegistry) Register(check HealthCheck) {
+	if check.Timeout <= 0 {
+		check.Timeout = DefaultHealthCheckTimeout
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, existing := range h.checks {
+		if existing.Name == check.Name {
+			h.checks[i] = &registeredCheck{HealthCheck: check}
+			return
+		}
+	}
+	h.checks = append(h.checks, &registeredCheck{HealthCheck: check})
+}
+
+// MarkStarted makes the startup and readiness probes run their checks
+func (h *HealthRegistry) MarkStarted() {
+	h.started.Store(true)
+}
+
+// SetDraining makes the readiness probe fail while the server drains
+func (h *HealthRegistry) SetDraining(draining bool) {
+	h.draining.Store(draining)
+}
+
+// Draining reports whether SetDraining(true) was called
+func (h *HealthRegistry) Draining() bool {
+	return h.draining.Load()
+}
+
+// Probe answers one of ProbeLive, ProbeReady or ProbeStartup. The report
+// fails if a critical check fails, the process has not started or, for
+// readiness, it is draining.
+func (h *HealthRegistry) Probe(ctx context.Context, probe string) HealthReport {
+	report := HealthReport{Probe: probe, Status: HealthOK, Checks: []HealthCheckResult{}}
+	switch probe {
+	case ProbeLive:
+		return report
+	case ProbeReady, ProbeStartup:
+	default:
+		report.Status, report.Reason = HealthFail, fmt.Sprintf("unknown probe %q", probe)
+		return report
+	}
+
+	switch {
+	case !h.started.Load():
+		report.Status, report.Reason = HealthFail, "starting"
+		return report
+	case probe == ProbeReady && h.draining.Load():
+		report.Status, report.Reason = HealthFail, "draining"
+		return report
+	}
+
+	h.mu.RLock()
+	checks := append([]*registeredCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make([]HealthCheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		switch {
+		case result.Status == HealthOK:
+		case result.Critical:
+			report.Status = HealthFail
+		case report.Status == HealthOK:
+			report.Status = HealthDegraded
+		}
+	}
+	report.Checks = results
+	return report
+}
+
+// run returns the cached result of check or runs it with its timeout
+func (h *HealthRegistry) run(ctx context.Context, check *registeredCheck) HealthCheckResult {
+	check.mu.Lock()
+	defer check.mu.Unlock()
+	if !check.last.CheckedAt.IsZero() && h.now().Sub(check.last.CheckedAt) < h.cacheTTL {
+		cached := check.last
+		cached.Cached = true
+		return cached
+	}
+
+	start := h.now()
+	err := runWithTimeout(ctx, check.Check, check.Timeout)
+	duration := h.now().Sub(start)
+
+	result := HealthCheckResult{
+		Name:       check.Name,
+		Status:     HealthOK,
+		Critical:   check.Critical,
+		DurationMS: float64(duration.Microseconds()) / 1000,
+		CheckedAt:  start,
+	}
+	if err != nil {
+		result.Status, result.Error = HealthFail, err.Error()
+		slog.WarnContext(ctx, "Health check failed", "check", check.Name, "critical", check.Critical, "error", err)
+	}
+	recordHealthCheck(ctx, check.Name, result.Status, duration)
+
+	// A run cut short by the caller going away says nothing about the dependency
+	if ctx.Err() == nil {
+		check.last = result
+	}
+	return result
+}
+
+// runWithTimeout runs check, giving up after timeout even if it ignores its context
+func runWithTimeout(ctx context.Context, check func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return ctx.Err()
+	}
+}
+
+// initHealthMetrics creates the health check instruments once
+func initHealthMetrics() {
+	var err error
+	healthCheckDuration, err = businessMeter.Float64Histogram(
+		"health.check.duration",
+		metric.WithDescription("Duration of health check runs by check and status"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 2, 5),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize health check metric", "error", err)
+	}
+}
+
+// recordHealthCheck records the duration of a check that actually ran
+func recordHealthCheck(ctx context.Context, name, status string, duration time.Duration) {
+	healthMetricsOnce.Do(initHealthMetrics)
+	if healthCheckDuration == nil {
+		return
+	}
+	healthCheckDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		healthCheckKey.String(name),
+		healthStatusKey.String(status),
+	))
+}
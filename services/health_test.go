@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthRegistryProbes(t *testing.T) {
+	h := NewHealthRegistry(time.Minute)
+	var storageErr error
+	h.Register(HealthCheck{Name: "storage", Critical: true, Check: func(context.Context) error { return storageErr }})
+	h.Register(HealthCheck{Name: "telemetry", Check: func(context.Context) error { return errors.New("export failed") }})
+
+	if report := h.Probe(context.Background(), ProbeStartup); report.Status != HealthFail || report.Reason != "starting" {
+		t.Errorf("Probes should fail before startup, got %+v", report)
+	}
+	if report := h.Probe(context.Background(), ProbeLive); report.Status != HealthOK {
+		t.Errorf("Liveness should not depend on startup, got %+v", report)
+	}
+
+	h.MarkStarted()
+	report := h.Probe(context.Background(), ProbeReady)
+	if report.Status != HealthDegraded || len(report.Checks) != 2 {
+		t.Fatalf("A failing non-critical check should degrade readiness, got %+v", report)
+	}
+	if report.Checks[1].Name != "telemetry" || report.Checks[1].Error != "export failed" {
+		t.Errorf("Unexpected check result: %+v", report.Checks[1])
+	}
+
+	h.Register(HealthCheck{Name: "storage", Critical: true, Check: func(context.Context) error { return errors.New("disk full") }})
+	if report := h.Probe(context.Background(), ProbeStartup); report.Status != HealthFail {
+		t.Errorf("A failing critical check should fail the probe, got %+v", report)
+	}
+
+	h.SetDraining(true)
+	if report := h.Probe(context.Background(), ProbeReady); report.Status != HealthFail || report.Reason != "draining" {
+		t.Errorf("Readiness should fail while draining, got %+v", report)
+	}
+	if report := h.Probe(context.Background(), ProbeLive); report.Status != HealthOK {
+		t.Errorf("Liveness should pass while draining, got %+v", report)
+	}
+}
+
+func TestHealthRegistryCachesResults(t *testing.T) {
+	h := NewHealthRegistry(time.Second)
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	h.MarkStarted()
+
+	var runs atomic.Int32
+	h.Register(HealthCheck{Name: "storage", Critical: true, Check: func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}})
+
+	h.Probe(context.Background(), ProbeReady)
+	report := h.Probe(context.Background(), ProbeReady)
+	if runs.Load() != 1 || !report.Checks[0].Cached {
+		t.Errorf("Expected one run and a cached result, got %d runs and %+v", runs.Load(), report.Checks[0])
+	}
+
+	now = now.Add(2 * time.Second)
+	if report := h.Probe(context.Background(), ProbeReady); runs.Load() != 2 || report.Checks[0].Cached {
+		t.Errorf("Expected the check to run again after the TTL, got %d runs", runs.Load())
+	}
+}
+
+func TestHealthRegistryTimeout(t *testing.T) {
+	h := NewHealthRegistry(0)
+	h.MarkStarted()
+	block := make(chan struct{})
+	defer close(block)
+	h.Register(HealthCheck{Name: "stuck", Critical: true, Timeout: 10 * time.Millisecond, Check: func(context.Context) error {
+		// Ignores its context, so only the registry's timeout can end the probe
+		<-block
+		return nil
+	}})
+
+	report := h.Probe(context.Background(), ProbeReady)
+	if report.Status != HealthFail || report.Checks[0].Error != "timed out after 10ms" {
+		t.Errorf("Expected a timeout failure, got %+v", report)
+	}
+}
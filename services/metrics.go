@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Farcaster client platform types; anything else is recorded as PlatformUnknown
+const (
+	PlatformWeb     = "web"
+	PlatformMobile  = "mobile"
+	PlatformUnknown = "unknown"
+)
+
+// Attribute keys of the business metrics
+const (
+	clickCounterKey    = attribute.Key("click.counter")
+	platformTypeKey    = attribute.Key("farcaster.platform_type")
+	authenticatedKey   = attribute.Key("user.authenticated")
+	pageTemplateKey    = attribute.Key("page.template")
+	sdkReadyOutcomeKey = attribute.Key("outcome")
+)
+
+var (
+	businessMeter = otel.Meter("hello-world/services")
+
+	clicksTotal         metric.Int64Counter
+	pageViews           metric.Int64Counter
+	sdkReadyTotal       metric.Int64Counter
+	sdkReadyDuration    metric.Float64Histogram
+	businessMetricsOnce sync.Once
+)
+
+// ClickAttributes describes a click for the clicks.total metric
+type ClickAttributes struct {
+	// Counter names the clicked counter; defaults to DefaultClickCounter
+	Counter string
+	// Platform is the Farcaster client platform type
+	Platform string
+	// Authenticated reports whether the click came from a signed-in Farcaster user
+	Authenticated bool
+}
+
+// initBusinessMetrics creates the product usage instruments once
+func initBusinessMetrics() {
+	var err error
+	clicksTotal, err = businessMeter.Int64Counter(
+		"clicks.total",
+		metric.WithDescription("Clicks on a counter by platform and authentication"),
+		metric.WithUnit("{click}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize click metric", "error", err)
+	}
+
+	_, err = businessMeter.Int64ObservableGauge(
+		"clicks.current",
+		metric.WithDescription("Current value of the click counter"),
+		metric.WithUnit("{click}"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(clickCount.Load(), metric.WithAttributes(clickCounterKey.String(DefaultClickCounter)))
+			return nil
+		}),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize click gauge", "error", err)
+	}
+
+	pageViews, err = businessMeter.Int64Counter(
+		"page.views",
+		metric.WithDescription("Rendered pages by template"),
+		metric.WithUnit("{view}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize page view metric", "error", err)
+	}
+
+	sdkReadyTotal, err = businessMeter.Int64Counter(
+		"miniapp.sdk.ready",
+		metric.WithDescription("Farcaster MiniApp SDK initializations reported by clients, by outcome"),
+		metric.WithUnit("{initialization}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize SDK ready metric", "error", err)
+	}
+
+	sdkReadyDuration, err = businessMeter.Float64Histogram(
+		"miniapp.sdk.ready.duration",
+		metric.WithDescription("Time from page script start until the MiniApp SDK reported ready"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize SDK ready duration metric", "error", err)
+	}
+}
+
+// NormalizePlatform maps a client-reported platform type to a low-cardinality value
+func NormalizePlatform(platform string) string {
+	switch p := strings.ToLower(strings.TrimSpace(platform)); p {
+	case PlatformWeb, PlatformMobile:
+		return p
+	}
+	return PlatformUnknown
+}
+
+// RecordClick counts a click on the clicks.total metric
+func RecordClick(ctx context.Context, attrs ClickAttributes) {
+	businessMetricsOnce.Do(initBusinessMetrics)
+	if clicksTotal == nil {
+		return
+	}
+	if attrs.Counter == "" {
+		attrs.Counter = DefaultClickCounter
+	}
+	clicksTotal.Add(ctx, 1, metric.WithAttributes(
+		clickCounterKey.String(attrs.Counter),
+		platformTypeKey.String(NormalizePlatform(attrs.Platform)),
+		authenticatedKey.Bool(attrs.Authenticated),
+	))
+}
+
+// RecordPageView counts a page sent in full, e.g. "home.html"; error pages and
+// 304 revalidations are not views
+func RecordPageView(ctx context.Context, template string) {
+	businessMetricsOnce.Do(initBusinessMetrics)
+	if pageViews == nil {
+		return
+	}
+	pageViews.Add(ctx, 1, metric.WithAttributes(pageTemplateKey.String(template)))
+}
+
+// RecordSDKReady records a MiniApp SDK initialization reported by the debug page
+func RecordSDKReady(ctx context.Context, platform string, success bool, duration time.Duration) {
+	businessMetricsOnce.Do(initBusinessMetrics)
+	outcome := "success"
+	if !success {
+		outcome = "error"
+	}
+	attrs := metric.WithAttributes(
+		platformTypeKey.String(NormalizePlatform(platform)),
+		sdkReadyOutcomeKey.String(outcome),
+	)
+	if sdkReadyTotal != nil {
+		sdkReadyTotal.Add(ctx, 1, attrs)
+	}
+	if sdkReadyDuration != nil && success && duration > 0 {
+		sdkReadyDuration.Record(ctx, duration.Seconds(), attrs)
+	}
+}
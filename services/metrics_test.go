@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizePlatform(t *testing.T) {
+	tests := map[string]string{
+		"web":     PlatformWeb,
+		" Mobile": PlatformMobile,
+		"":        PlatformUnknown,
+		"desktop": PlatformUnknown,
+	}
+	for input, want := range tests {
+		if got := NormalizePlatform(input); got != want {
+			t.Errorf("NormalizePlatform(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestClickIncrementsCount(t *testing.T) {
+	service := NewClickService()
+	service.Reset()
+
+	attrs := ClickAttributes{Counter: DefaultClickCounter, Platform: "web", Authenticated: true}
+	if count := service.Click(context.Background(), attrs); count != 1 {
+		t.Errorf("Expected count 1, got %d", count)
+	}
+	if count := service.Click(context.Background(), ClickAttributes{}); count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+	if count := service.GetCount(); count != 2 {
+		t.Errorf("Expected GetCount 2, got %d", count)
+	}
+}
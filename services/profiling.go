@@ -0,0 +1,275 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Profile kinds captured by CaptureProfiles
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+	ProfileTrace     = "trace"
+)
+
+// DefaultProfileKinds are captured when no kinds are requested
+var DefaultProfileKinds = []string{ProfileCPU, ProfileHeap, ProfileGoroutine, ProfileTrace}
+
+// ErrProfilerBusy is returned when another CPU profile or execution trace is
+// running; the runtime supports only one of each at a time
+var ErrProfilerBusy = errors.New("a CPU profile or execution trace is already running")
+
+// CaptureOptions configures CaptureProfiles
+type CaptureOptions struct {
+	// Duration is how long the CPU profile and execution trace run
+	Duration time.Duration
+	// Kinds selects the profiles; defaults to DefaultProfileKinds
+	Kinds []string
+	// Commit labels the capture, normally config.CommitHash
+	Commit string
+}
+
+// ProfileSet is the result of one capture
+type ProfileSet struct {
+	Commit     string
+	CapturedAt time.Time
+	Duration   time.Duration
+	// Files maps file names such as "cpu.pprof" or "trace.out" to their contents
+	Files map[string][]byte
+}
+
+// CaptureProfiles records the requested profiles. CPU profiles and traces run
+// for opts.Duration, or until ctx is done, in which case ctx.Err is returned;
+// heap and goroutine profiles are snapshots taken at the end.
+func CaptureProfiles(ctx context.Context, opts CaptureOptions) (*ProfileSet, error) {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultProfileKinds
+	}
+	for _, kind := range kinds {
+		if !slices.Contains(DefaultProfileKinds, kind) {
+			return nil, fmt.Errorf("unknown profile kind %q", kind)
+		}
+	}
+
+	set := &ProfileSet{Commit: opts.Commit, CapturedAt: time.Now().UTC(), Files: make(map[string][]byte)}
+	cpuProfile, execTrace := slices.Contains(kinds, ProfileCPU), slices.Contains(kinds, ProfileTrace)
+	var cpu, exec bytes.Buffer
+	stop := func() {
+		if cpuProfile {
+			pprof.StopCPUProfile()
+		}
+		if execTrace {
+			trace.Stop()
+		}
+	}
+	if cpuProfile {
+		if err := pprof.StartCPUProfile(&cpu); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrProfilerBusy, err)
+		}
+	}
+	if execTrace {
+		if err := trace.Start(&exec); err != nil {
+			if cpuProfile {
+				pprof.StopCPUProfile()
+			}
+			return nil, fmt.Errorf("%w: %v", ErrProfilerBusy, err)
+		}
+	}
+
+	if cpuProfile || execTrace {
+		timer := time.NewTimer(opts.Duration)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			stop()
+			return nil, ctx.Err()
+		}
+		stop()
+		set.Duration = opts.Duration
+	}
+	if cpuProfile {
+		set.Files["cpu.pprof"] = cpu.Bytes()
+	}
+	if execTrace {
+		set.Files["trace.out"] = exec.Bytes()
+	}
+	for _, kind := range []string{ProfileHeap, ProfileGoroutine} {
+		if !slices.Contains(kinds, kind) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := pprof.Lookup(kind).WriteTo(&buf, 0); err != nil {
+			return nil, fmt.Errorf("writing %s profile: %w", kind, err)
+		}
+		set.Files[kind+".pprof"] = buf.Bytes()
+	}
+	return set, nil
+}
+
+// metadata describes the capture for the archive's metadata.json
+func (s *ProfileSet) metadata() ([]byte, error) {
+	hostname, _ := os.Hostname()
+	return json.MarshalIndent(map[string]any{
+		"commit":      s.Commit,
+		"go_version":  runtime.Version(),
+		"hostname":    hostname,
+		"captured_at": s.CapturedAt.Format(time.RFC3339),
+		"duration":    s.Duration.String(),
+	}, "", "  ")
+}
+
+// ArchiveName is the suggested file name of the archive, e.g. "profiles-abc123-20250801T120000Z.tar.gz"
+func (s *ProfileSet) ArchiveName() string {
+	return fmt.Sprintf("profiles-%s-%s.tar.gz", s.Commit, s.CapturedAt.Format("20060102T150405Z"))
+}
+
+// WriteArchive writes the profiles and a metadata.json as a gzipped tarball
+func (s *ProfileSet) WriteArchive(w io.Writer) error {
+	meta, err := s.metadata()
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	files := map[string][]byte{"metadata.json": meta}
+	for name, content := range s.Files {
+		files[name] = content
+	}
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(files[name])), ModTime: s.CapturedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// WriteDir writes each profile to dir as "<time>_<commit>_<file>"
+func (s *ProfileSet) WriteDir(dir string) error {
+	prefix := s.CapturedAt.Format("20060102T150405Z") + "_" + s.Commit + "_"
+	for name, content := range s.Files {
+		if err := os.WriteFile(filepath.Join(dir, prefix+name), content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContinuousProfilerOptions configures ContinuousProfiler
+type ContinuousProfilerOptions struct {
+	// Dir receives the profiles; it is created if missing
+	Dir string
+	// Interval is the time between captures
+	Interval time.Duration
+	// CPUDuration is the length of each CPU profile
+	CPUDuration time.Duration
+	// Retention is how long profiles are kept; zero keeps them forever
+	Retention time.Duration
+	// Commit labels every file, normally config.CommitHash
+	Commit string
+}
+
+// ContinuousProfiler periodically writes CPU, heap and goroutine profiles to a
+// local directory and prunes those older than the retention
+type ContinuousProfiler struct {
+	opts ContinuousProfilerOptions
+}
+
+// NewContinuousProfiler creates a profiler; call Run to start it
+func NewContinuousProfiler(opts ContinuousProfilerOptions) *ContinuousProfiler {
+	return &ContinuousProfiler{opts: opts}
+}
+
+// Run captures profiles every Interval until ctx is done
+func (p *ContinuousProfiler) Run(ctx context.Context) error {
+	if p.opts.Interval <= 0 || p.opts.CPUDuration >= p.opts.Interval {
+		return fmt.Errorf("profile interval %s must be positive and longer than the CPU duration %s", p.opts.Interval, p.opts.CPUDuration)
+	}
+	if err := os.MkdirAll(p.opts.Dir, 0o755); err != nil {
+		return err
+	}
+	slog.Info("Continuous profiling enabled", "dir", p.opts.Dir, "interval", p.opts.Interval.String())
+
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			p.capture(ctx)
+		}
+	}
+}
+
+func (p *ContinuousProfiler) capture(ctx context.Context) {
+	set, err := CaptureProfiles(ctx, CaptureOptions{
+		Duration: p.opts.CPUDuration,
+		Kinds:    []string{ProfileCPU, ProfileHeap, ProfileGoroutine},
+		Commit:   p.opts.Commit,
+	})
+	switch {
+	case errors.Is(err, ErrProfilerBusy):
+		// An on-demand capture is running; try again next interval
+		slog.Debug("Skipping continuous profile", "error", err)
+		return
+	case err != nil:
+		if ctx.Err() == nil {
+			slog.Warn("Continuous profile failed", "error", err)
+		}
+		return
+	}
+	if err := set.WriteDir(p.opts.Dir); err != nil {
+		slog.Warn("Failed to write continuous profile", "dir", p.opts.Dir, "error", err)
+	}
+	p.prune(time.Now())
+}
+
+// prune removes profiles older than the retention
+func (p *ContinuousProfiler) prune(now time.Time) {
+	if p.opts.Retention <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(p.opts.Dir)
+	if err != nil {
+		slog.Warn("Failed to list profiles", "dir", p.opts.Dir, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".pprof") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < p.opts.Retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(p.opts.Dir, entry.Name())); err != nil {
+			slog.Warn("Failed to remove old profile", "file", entry.Name(), "error", err)
+		}
+	}
+}
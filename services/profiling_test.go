@@ -0,0 +1,93 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCaptureProfilesArchive(t *testing.T) {
+	set, err := CaptureProfiles(context.Background(), CaptureOptions{
+		Duration: 50 * time.Millisecond,
+		Kinds:    []string{ProfileCPU, ProfileHeap, ProfileGoroutine},
+		Commit:   "abc123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := set.WriteArchive(&buf); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	want := []string{"cpu.pprof", "goroutine.pprof", "heap.pprof", "metadata.json"}
+	if !slices.Equal(names, want) {
+		t.Errorf("Archive contains %v, want %v", names, want)
+	}
+	if name := set.ArchiveName(); !bytes.Contains([]byte(name), []byte("abc123")) {
+		t.Errorf("Archive name should carry the commit, got %q", name)
+	}
+}
+
+func TestCaptureProfilesBusy(t *testing.T) {
+	if err := pprof.StartCPUProfile(io.Discard); err != nil {
+		t.Skip("CPU profiler already running")
+	}
+	defer pprof.StopCPUProfile()
+
+	_, err := CaptureProfiles(context.Background(), CaptureOptions{Duration: time.Millisecond, Kinds: []string{ProfileCPU}})
+	if !errors.Is(err, ErrProfilerBusy) {
+		t.Errorf("Expected ErrProfilerBusy, got %v", err)
+	}
+}
+
+func TestContinuousProfilerPrune(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "20250101T000000Z_abc123_cpu.pprof")
+	recent := filepath.Join(dir, "20250102T000000Z_abc123_cpu.pprof")
+	other := filepath.Join(dir, "notes.txt")
+	for _, name := range []string{old, recent, other} {
+		if err := os.WriteFile(name, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	os.Chtimes(old, now.Add(-48*time.Hour), now.Add(-48*time.Hour))
+	os.Chtimes(other, now.Add(-48*time.Hour), now.Add(-48*time.Hour))
+
+	NewContinuousProfiler(ContinuousProfilerOptions{Dir: dir, Retention: 24 * time.Hour}).prune(now)
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected the expired profile to be removed")
+	}
+	for _, name := range []string{recent, other} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Expected %s to be kept: %v", filepath.Base(name), err)
+		}
+	}
+}
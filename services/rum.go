@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Web Vitals accepted from the RUM beacon
+const (
+	WebVitalLCP  = "LCP"
+	WebVitalINP  = "INP"
+	WebVitalCLS  = "CLS"
+	WebVitalTTFB = "TTFB"
+)
+
+// Web Vital ratings, using the thresholds published at web.dev/vitals
+const (
+	RatingGood             = "good"
+	RatingNeedsImprovement = "needs-improvement"
+	RatingPoor             = "poor"
+)
+
+// webVitalThresholds are the upper bounds of the good and needs-improvement
+// ratings, in milliseconds except for the unitless CLS score
+var webVitalThresholds = map[string][2]float64{
+	WebVitalLCP:  {2500, 4000},
+	WebVitalINP:  {200, 500},
+	WebVitalCLS:  {0.1, 0.25},
+	WebVitalTTFB: {800, 1800},
+}
+
+// Attribute keys of the RUM metrics
+const (
+	webVitalNameKey   = attribute.Key("web_vital.name")
+	webVitalRatingKey = attribute.Key("web_vital.rating")
+	clientErrorKind   = attribute.Key("error.kind")
+)
+
+var (
+	rumWebVitals   metric.Float64Histogram
+	rumLayoutShift metric.Float64Histogram
+	rumErrors      metric.Int64Counter
+	rumHTMXTimings metric.Float64Histogram
+	rumMetricsOnce sync.Once
+)
+
+// initRUMMetrics creates the instruments fed by the browser beacon once
+func initRUMMetrics() {
+	var err error
+	rumWebVitals, err = businessMeter.Float64Histogram(
+		"rum.web_vital.duration",
+		metric.WithDescription("Timing Web Vitals (LCP, INP, TTFB) reported by browsers"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.05, 0.1, 0.2, 0.5, 0.8, 1, 1.8, 2.5, 4, 6, 10),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize Web Vitals metric", "error", err)
+	}
+
+	rumLayoutShift, err = businessMeter.Float64Histogram(
+		"rum.web_vital.cls",
+		metric.WithDescription("Cumulative Layout Shift scores reported by browsers"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(0.01, 0.05, 0.1, 0.15, 0.25, 0.5, 1),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize layout shift metric", "error", err)
+	}
+
+	rumErrors, err = businessMeter.Int64Counter(
+		"rum.errors",
+		metric.WithDescription("Uncaught JavaScript errors and unhandled rejections reported by browsers"),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize client error metric", "error", err)
+	}
+
+	rumHTMXTimings, err = businessMeter.Float64Histogram(
+		"rum.htmx.request.duration",
+		metric.WithDescription("HTMX request durations measured in the browser, including network time"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10),
+	)
+	if err != nil {
+		slog.Warn("Failed to initialize HTMX timing metric", "error", err)
+	}
+}
+
+// IsWebVital reports whether name is one of the accepted Web Vitals
+func IsWebVital(name string) bool {
+	_, ok := webVitalThresholds[name]
+	return ok
+}
+
+// WebVitalRating rates a Web Vital value, in milliseconds or as a CLS score
+func WebVitalRating(name string, value float64) string {
+	thresholds, ok := webVitalThresholds[name]
+	switch {
+	case !ok:
+		return ""
+	case value <= thresholds[0]:
+		return RatingGood
+	case value <= thresholds[1]:
+		return RatingNeedsImprovement
+	}
+	return RatingPoor
+}
+
+// RecordWebVital records a Web Vital measured in milliseconds, or a CLS score
+func RecordWebVital(ctx context.Context, name string, value float64, platform string) {
+	rumMetricsOnce.Do(initRUMMetrics)
+	attrs := metric.WithAttributes(
+		webVitalNameKey.String(name),
+		webVitalRatingKey.String(WebVitalRating(name, value)),
+		platformTypeKey.String(NormalizePlatform(platform)),
+	)
+	if name == WebVitalCLS {
+		if rumLayoutShift != nil {
+			rumLayoutShift.Record(ctx, value, attrs)
+		}
+		return
+	}
+	if rumWebVitals != nil {
+		rumWebVitals.Record(ctx, value/1000, attrs)
+	}
+}
+
+// RecordClientError counts a browser error of kind "error" or "unhandledrejection"
+func RecordClientError(ctx context.Context, kind string, platform string) {
+	rumMetricsOnce.Do(initRUMMetrics)
+	if rumErrors == nil {
+		return
+	}
+	rumErrors.Add(ctx, 1, metric.WithAttributes(
+		clientErrorKind.String(kind),
+		platformTypeKey.String(NormalizePlatform(platform)),
+	))
+}
+
+// RecordHTMXTiming records an HTMX request as seen by the browser; status is
+// zero when the request failed without a response
+func RecordHTMXTiming(ctx context.Context, method string, status int, duration time.Duration) {
+	rumMetricsOnce.Do(initRUMMetrics)
+	if rumHTMXTimings == nil {
+		return
+	}
+	statusClass := "error"
+	if status > 0 {
+		statusClass = strconv.Itoa(status/100) + "xx"
+	}
+	rumHTMXTimings.Record(ctx, duration.Seconds(), metric.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(method),
+		attribute.String("http.response.status_class", statusClass),
+	))
+}
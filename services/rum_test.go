@@ -0,0 +1,24 @@
+package services
+
+import "testing"
+
+func TestWebVitalRating(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+		want  string
+	}{
+		{WebVitalLCP, 2500, RatingGood},
+		{WebVitalLCP, 3000, RatingNeedsImprovement},
+		{WebVitalINP, 650, RatingPoor},
+		{WebVitalCLS, 0.05, RatingGood},
+		{WebVitalCLS, 0.3, RatingPoor},
+		{WebVitalTTFB, 1000, RatingNeedsImprovement},
+		{"FID", 10, ""},
+	}
+	for _, tt := range tests {
+		if got := WebVitalRating(tt.name, tt.value); got != tt.want {
+			t.Errorf("WebVitalRating(%s, %v) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ShutdownPhase is one step of a graceful shutdown
+type ShutdownPhase struct {
+	Name string
+	// Budget bounds the phase; zero gives it no time beyond an immediate check of its context
+	Budget time.Duration
+	Run    func(ctx context.Context) error
+}
+
+// RunShutdown runs phases in order, each with a context bounded by its budget.
+// A phase that fails or overruns is logged and the next one still runs, so
+// later phases such as flushing telemetry are never skipped.
+func RunShutdown(ctx context.Context, phases []ShutdownPhase) {
+	start := time.Now()
+	for _, phase := range phases {
+		phaseStart := time.Now()
+		slog.InfoContext(ctx, "Shutdown phase started", "phase", phase.Name, "budget", phase.Budget.String())
+
+		phaseCtx, cancel := context.WithTimeout(ctx, phase.Budget)
+		err := phase.Run(phaseCtx)
+		cancel()
+
+		attrs := []any{"phase", phase.Name, "duration", time.Since(phaseStart).String()}
+		if err != nil {
+			slog.WarnContext(ctx, "Shutdown phase did not complete", append(attrs, "error", err)...)
+		} else {
+			slog.InfoContext(ctx, "Shutdown phase finished", attrs...)
+		}
+	}
+	slog.InfoContext(ctx, "Shutdown complete", "duration", time.Since(start).String())
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunShutdownRunsEveryPhase(t *testing.T) {
+	var ran []string
+	RunShutdown(context.Background(), []ShutdownPhase{
+		{Name: "readiness", Run: func(context.Context) error {
+			ran = append(ran, "readiness")
+			return nil
+		}},
+		{Name: "in_flight", Budget: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+			ran = append(ran, "in_flight")
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		{Name: "failing", Budget: time.Second, Run: func(context.Context) error {
+			ran = append(ran, "failing")
+			return errors.New("exporter unreachable")
+		}},
+		{Name: "flush", Budget: time.Second, Run: func(ctx context.Context) error {
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < 500*time.Millisecond {
+				t.Errorf("Expected the phase's own budget, got deadline %v", deadline)
+			}
+			ran = append(ran, "flush")
+			return nil
+		}},
+	})
+
+	if want := []string{"readiness", "in_flight", "failing", "flush"}; !slices.Equal(ran, want) {
+		t.Errorf("Expected phases %v, got %v", want, ran)
+	}
+}
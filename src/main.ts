@@ -3,11 +3,168 @@
 // Your TypeScript code goes here
 console.log('Hello from TypeScript!');
 
+// Real User Monitoring: Web Vitals, uncaught errors and htmx timings are
+// batched and sent to /api/rum, tagged with the traceparent of the page load
+type RUMEvent = Record<string, string | number>;
+
+const rumQueue: RUMEvent[] = [];
+const rumMaxBatch = 20;
+
+function flushRUM() {
+  if (rumQueue.length === 0) return;
+  const traceparent = document.querySelector<HTMLMetaElement>('meta[name="traceparent"]')?.content ?? '';
+  const body = JSON.stringify({
+    traceparent,
+    page: location.pathname,
+    platform: sessionStorage.getItem('farcasterPlatform') ?? '',
+    events: rumQueue.splice(0, rumMaxBatch),
+  });
+  if (!navigator.sendBeacon?.('/api/rum', body)) {
+    fetch('/api/rum', { method: 'POST', body, keepalive: true }).catch(() => {});
+  }
+  if (rumQueue.length > 0) flushRUM();
+}
+
+function queueRUM(event: RUMEvent) {
+  rumQueue.push(event);
+  if (rumQueue.length >= rumMaxBatch) flushRUM();
+}
+
+function observe(type: string, callback: (entries: any[]) => void, options: Record<string, unknown> = {}) {
+  try {
+    new PerformanceObserver((list) => callback(list.getEntries())).observe({ type, buffered: true, ...options } as PerformanceObserverInit);
+  } catch {
+    // Entry type not supported by this browser
+  }
+}
+
+// Final vitals are reported once, when the page is hidden
+let lcp = -1;
+let cls = 0;
+let inp = -1;
+let clsWindow = 0;
+let clsWindowStart = 0;
+let clsLastShift = 0;
+let vitalsReported = false;
+
+observe('largest-contentful-paint', (entries) => {
+  const last = entries[entries.length - 1];
+  if (last) lcp = last.startTime;
+});
+
+observe('layout-shift', (entries) => {
+  // CLS is the largest burst of shifts less than 1s apart within a 5s window
+  for (const entry of entries) {
+    if (entry.hadRecentInput) continue;
+    if (entry.startTime - clsLastShift > 1000 || entry.startTime - clsWindowStart > 5000) {
+      clsWindow = 0;
+      clsWindowStart = entry.startTime;
+    }
+    clsWindow += entry.value;
+    clsLastShift = entry.startTime;
+    cls = Math.max(cls, clsWindow);
+  }
+});
+
+observe('event', (entries) => {
+  // Approximates INP as the slowest interaction, which matches its p98 below 50 interactions
+  for (const entry of entries) {
+    if (entry.interactionId) inp = Math.max(inp, entry.duration);
+  }
+}, { durationThreshold: 40 });
+
+const [navigation] = performance.getEntriesByType('navigation') as PerformanceNavigationTiming[];
+if (navigation && navigation.responseStart > 0) {
+  queueRUM({ type: 'vital', name: 'TTFB', value: navigation.responseStart });
+}
+
+function reportVitals() {
+  if (vitalsReported) return;
+  vitalsReported = true;
+  if (lcp >= 0) queueRUM({ type: 'vital', name: 'LCP', value: lcp });
+  if (inp >= 0) queueRUM({ type: 'vital', name: 'INP', value: inp });
+  queueRUM({ type: 'vital', name: 'CLS', value: cls });
+}
+
+document.addEventListener('visibilitychange', () => {
+  if (document.visibilityState !== 'hidden') return;
+  reportVitals();
+  flushRUM();
+});
+window.addEventListener('pagehide', () => {
+  reportVitals();
+  flushRUM();
+});
+
+window.addEventListener('error', (event) => {
+  queueRUM({
+    type: 'error',
+    name: 'error',
+    message: String(event.message ?? ''),
+    source: event.filename ?? '',
+    line: event.lineno ?? 0,
+    column: event.colno ?? 0,
+  });
+});
+window.addEventListener('unhandledrejection', (event) => {
+  const reason = event.reason;
+  queueRUM({ type: 'error', name: 'unhandledrejection', message: String(reason?.message ?? reason) });
+});
+
 // Example: Add custom htmx behavior
 document.addEventListener('DOMContentLoaded', () => {
   if (window.htmx) {
     window.htmx.on('htmx:afterRequest', (event: any) => {
       console.log('HTMX request completed:', event.detail);
     });
+
+    // Time htmx requests for RUM; the beacon itself is sent with sendBeacon, not htmx
+    const htmxStarts = new WeakMap<XMLHttpRequest, number>();
+    window.htmx.on('htmx:beforeRequest', (event: any) => {
+      htmxStarts.set(event.detail.xhr, performance.now());
+    });
+    window.htmx.on('htmx:afterRequest', (event: any) => {
+      const started = htmxStarts.get(event.detail.xhr);
+      if (started === undefined) return;
+      queueRUM({
+        type: 'htmx',
+        method: String(event.detail.requestConfig?.verb ?? 'get').toUpperCase(),
+        path: String(event.detail.pathInfo?.requestPath ?? '').split('?')[0],
+        status: event.detail.xhr.status,
+        duration_ms: performance.now() - started,
+      });
+    });
+
+    // Tag requests with the MiniApp platform recorded by the debug page for click metrics
+    window.htmx.on('htmx:configRequest', (event: any) => {
+      const platform = sessionStorage.getItem('farcasterPlatform');
+      if (platform) event.detail.headers['X-Farcaster-Platform'] = platform;
+    });
+
+    // Authenticate admin requests, such as the diagnostics polling on /debug,
+    // with the token entered on the page; without one they are not sent at all
+    window.htmx.on('htmx:configRequest', (event: any) => {
+      if (!String(event.detail.path).startsWith('/admin/')) return;
+      const token = sessionStorage.getItem('adminToken');
+      if (!token) {
+        event.preventDefault();
+        return;
+      }
+      event.detail.headers['Authorization'] = `Bearer ${token}`;
+    });
+
+    // Forget a rejected admin token so polling stops instead of failing every few seconds
+    window.htmx.on('htmx:afterRequest', (event: any) => {
+      if (event.detail.xhr?.status === 403 && String(event.detail.pathInfo?.requestPath ?? '').startsWith('/admin/')) {
+        sessionStorage.removeItem('adminToken');
+      }
+    });
+
+    // Dismiss error toasts a few seconds after they are swapped in
+    window.htmx.on('htmx:afterSwap', (event: any) => {
+      if (event.detail.target?.id !== 'toast-region') return;
+      const toast = event.detail.target.lastElementChild;
+      setTimeout(() => toast?.remove(), 5000);
+    });
   }
 });
\ No newline at end of file
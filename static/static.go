@@ -0,0 +1,11 @@
+// Package static embeds the built frontend assets so the binary can serve
+// them without a static/ directory on disk.
+package static
+
+import "embed"
+
+// FS holds the files under static/, including this source file, which the
+// asset service ignores
+//
+//go:embed *
+var FS embed.FS